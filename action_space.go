@@ -59,10 +59,29 @@ type Entropyer interface {
 	Entropy(params anydiff.Res, batchSize int) anydiff.Res
 }
 
+// A Moder can compute the mode of a parametric
+// distribution.
+//
+// Modes are useful for evaluating policies without any
+// exploration noise.
+type Moder interface {
+	// Mode computes a batch of the most likely samples
+	// given a batch of parameter vectors.
+	Mode(params anyvec.Vector, batchSize int) anyvec.Vector
+}
+
 // Softmax is an action space which applies the softmax
 // function to obtain a categorical distribution.
 // It produces one-hot vector samples.
-type Softmax struct{}
+type Softmax struct {
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	//
+	// Since a rand.Rand is not thread-safe, a Softmax with
+	// a non-nil Rand should not be used from multiple
+	// Goroutines at once.
+	Rand *rand.Rand
+}
 
 // Sample samples one-hot vectors from the softmax
 // distribution.
@@ -81,12 +100,37 @@ func (s Softmax) Sample(params anyvec.Vector, batch int) anyvec.Vector {
 	var oneHots []float64
 	for i := 0; i < batch; i++ {
 		subset := probBatch[i*chunkSize : (i+1)*chunkSize]
-		oneHots = append(oneHots, sampleProbabilities(subset)...)
+		oneHots = append(oneHots, sampleProbabilities(s.Rand, subset)...)
 	}
 
 	return anyvec.Make(p.Creator(), oneHots)
 }
 
+// Mode produces one-hot vectors for the most likely
+// classes.
+func (s Softmax) Mode(params anyvec.Vector, batch int) anyvec.Vector {
+	if params.Len()%batch != 0 {
+		panic("batch size must divide parameter count")
+	}
+
+	chunkSize := params.Len() / batch
+	paramSlice := params.Creator().Float64Slice(params.Data())
+
+	oneHots := make([]float64, len(paramSlice))
+	for i := 0; i < batch; i++ {
+		subset := paramSlice[i*chunkSize : (i+1)*chunkSize]
+		var maxIdx int
+		for j, x := range subset {
+			if x > subset[maxIdx] {
+				maxIdx = j
+			}
+		}
+		oneHots[i*chunkSize+maxIdx] = 1
+	}
+
+	return anyvec.Make(params.Creator(), oneHots)
+}
+
 // LogProb computes the output log probabilities.
 func (s Softmax) LogProb(params anydiff.Res, output anyvec.Vector,
 	batchSize int) anydiff.Res {
@@ -146,6 +190,10 @@ type Bernoulli struct {
 	// one-hot vectors with two components.
 	// If false, samples are binary values (0 or 1).
 	OneHot bool
+
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
 }
 
 // Sample samples Bernoulli random variables.
@@ -154,7 +202,7 @@ func (b *Bernoulli) Sample(params anyvec.Vector, batch int) anyvec.Vector {
 	anyvec.Sigmoid(probs)
 
 	cutoffs := params.Creator().MakeVector(params.Len())
-	anyvec.Rand(cutoffs, anyvec.Uniform, nil)
+	anyvec.Rand(cutoffs, anyvec.Uniform, b.Rand)
 
 	// Turn probs into a sampled binary vector.
 	probs.Sub(cutoffs)
@@ -167,6 +215,16 @@ func (b *Bernoulli) Sample(params anyvec.Vector, batch int) anyvec.Vector {
 	}
 }
 
+// Mode produces the most likely binary values.
+func (b *Bernoulli) Mode(params anyvec.Vector, batch int) anyvec.Vector {
+	res := params.Copy()
+	anyvec.GreaterThan(res, params.Creator().MakeNumeric(0))
+	if b.OneHot {
+		return pairWithComplement(anydiff.NewConst(res)).Output()
+	}
+	return res
+}
+
 // LogProb computes the output log probabilities.
 func (b *Bernoulli) LogProb(params anydiff.Res, output anyvec.Vector,
 	batch int) anydiff.Res {
@@ -219,7 +277,11 @@ func (b *Bernoulli) offOnProbs(params anydiff.Res) anydiff.Res {
 // can only be positive.
 // To deal with this, the variance parameter is fed into
 // the exponential function.
-type Gaussian struct{}
+type Gaussian struct {
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+}
 
 // Sample samples continuous values from the distribution.
 func (g Gaussian) Sample(params anyvec.Vector, batchSize int) anyvec.Vector {
@@ -236,13 +298,20 @@ func (g Gaussian) Sample(params anyvec.Vector, batchSize int) anyvec.Vector {
 	anyvec.Exp(stddev)
 
 	noise := c.MakeVector(stddev.Len())
-	anyvec.Rand(noise, anyvec.Normal, nil)
+	anyvec.Rand(noise, anyvec.Normal, g.Rand)
 	noise.Mul(stddev)
 	noise.Add(mean)
 
 	return noise
 }
 
+// Mode produces the means of the distributions.
+func (g Gaussian) Mode(params anyvec.Vector, batchSize int) anyvec.Vector {
+	transParams := params.Creator().MakeVector(params.Len())
+	anyvec.Transpose(params, transParams, params.Len()/2)
+	return transParams.Slice(0, transParams.Len()/2).Copy()
+}
+
 // LogProb computes the output log densities.
 func (g Gaussian) LogProb(params anydiff.Res, output anyvec.Vector,
 	batchSize int) anydiff.Res {
@@ -328,12 +397,7 @@ func (g Gaussian) Entropy(params anydiff.Res, batchSize int) anydiff.Res {
 }
 
 func (g Gaussian) splitParams(params anydiff.Res) (mean, logVariance anydiff.Res) {
-	halfLen := params.Output().Len() / 2
-	mat := &anydiff.Matrix{Data: params, Rows: halfLen, Cols: 2}
-	tr := anydiff.Transpose(mat)
-	mean = anydiff.Slice(tr.Data, 0, halfLen)
-	logVariance = anydiff.Slice(tr.Data, halfLen, halfLen*2)
-	return
+	return splitPairs(params)
 }
 
 // Tuple is a tuple of action spaces which itself serves
@@ -371,6 +435,20 @@ func (t *Tuple) Sample(params anyvec.Vector, batch int) anyvec.Vector {
 	return packTuples(sampled, batch)
 }
 
+// Mode computes the modes of the tuple elements and
+// returns a packed tuple of results.
+//
+// This panics if a sub-space is not a Moder.
+func (t *Tuple) Mode(params anyvec.Vector, batch int) anyvec.Vector {
+	unpacked := unpackTuples(anydiff.NewConst(params), t.ParamSizes, batch)
+	var modes []anyvec.Vector
+	for i, subParams := range unpacked {
+		moder := t.Spaces[i].(Moder)
+		modes = append(modes, moder.Mode(subParams.Output(), batch))
+	}
+	return packTuples(modes, batch)
+}
+
 // LogProb computes the joint probability of the sampled
 // output.
 //
@@ -452,8 +530,15 @@ func batchedDot(vecs1, vecs2 anydiff.Res, batchSize int) anydiff.Res {
 
 // sampleProbabilities samples a one-hot vector from a
 // list of index probabilities.
-func sampleProbabilities(p []float64) []float64 {
-	randNum := rand.Float64()
+//
+// If gen is nil, the global generator is used.
+func sampleProbabilities(gen *rand.Rand, p []float64) []float64 {
+	var randNum float64
+	if gen == nil {
+		randNum = rand.Float64()
+	} else {
+		randNum = gen.Float64()
+	}
 	idx := len(p) - 1
 	for i, x := range p {
 		randNum -= x
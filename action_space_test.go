@@ -79,6 +79,17 @@ func TestSoftmaxEntropy(t *testing.T) {
 	assertSimilar(t, actual, expected)
 }
 
+func TestSoftmaxMode(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	in := c.MakeVectorData([]float64{
+		0.0902265411093121, -1.1492330740032015, -0.7417678904738725,
+		0.1571149104608501, -1.3123382994428667, 1.2192607242291933,
+	})
+	actual := Softmax{}.Mode(in, 2)
+	expected := c.MakeVectorData([]float64{1, 0, 0, 0, 0, 1})
+	assertSimilar(t, actual, expected)
+}
+
 func TestBernoulliSample(t *testing.T) {
 	c := anyvec64.DefaultCreator{}
 	in := c.MakeVectorData([]float64{
@@ -317,6 +328,35 @@ func TestGaussianEntropy(t *testing.T) {
 	}
 }
 
+func TestGaussianMode(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	in := c.MakeVectorData([]float64{
+		0.5, -1, 0.7, 2, -0.3, 0,
+	})
+	actual := Gaussian{}.Mode(in, 1)
+	expected := c.MakeVectorData([]float64{0.5, 0.7, -0.3})
+	assertSimilar(t, actual, expected)
+}
+
+func TestTupleMode(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	tuple := &Tuple{
+		Spaces:      []interface{}{Softmax{}, &Bernoulli{}, Gaussian{}},
+		ParamSizes:  []int{3, 2, 2},
+		SampleSizes: []int{3, 2, 1},
+	}
+	in := c.MakeVectorData([]float64{
+		0.1, 0.5, -0.2, 1, -1, 0.3, 2,
+		0.9, 0.5, -0.2, -1, 1, -0.7, 0,
+	})
+	actual := tuple.Mode(in, 2)
+	expected := c.MakeVectorData([]float64{
+		0, 1, 0, 1, 0, 0.3,
+		1, 0, 0, 0, 1, -0.7,
+	})
+	assertSimilar(t, actual, expected)
+}
+
 func TestTupleSample(t *testing.T) {
 	c := anyvec64.DefaultCreator{}
 	in := c.MakeVectorData([]float64{
@@ -4,6 +4,7 @@
 package anya3c
 
 import (
+	"context"
 	"sync"
 
 	"github.com/unixpickle/anyrl"
@@ -39,20 +40,24 @@ type A3C struct {
 //
 // If the done channel is closed, this finishes gracefully
 // and returns nil.
+// Pending environment calls are cancelled using
+// anyrl.EnvStepCtx and anyrl.EnvResetCtx, so a hung
+// environment cannot prevent Run from returning.
+//
 // If any environment produces an error, this stops and
 // returns the error.
 func (a *A3C) Run(envs []anyrl.Env, done <-chan struct{}) (err error) {
 	defer essentials.AddCtxTo("run A3C", &err)
 
 	errChan := make(chan error, len(envs))
-	stopChan := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
 
 	var wg sync.WaitGroup
 	for i, e := range envs {
 		wg.Add(1)
 		go func(i int, e anyrl.Env) {
 			defer wg.Done()
-			if err := a.worker(i, e, stopChan); err != nil {
+			if err := a.worker(ctx, i, e); err != nil {
 				errChan <- err
 			}
 		}(i, e)
@@ -62,14 +67,21 @@ func (a *A3C) Run(envs []anyrl.Env, done <-chan struct{}) (err error) {
 	case err = <-errChan:
 	case <-done:
 	}
-	close(stopChan)
+	cancel()
 
 	wg.Wait()
 	return
 }
 
-func (a *A3C) worker(id int, env anyrl.Env, stopChan <-chan struct{}) error {
-	w, err := newWorker(a.Creator, id, env, a.ParamServer)
+func (a *A3C) worker(ctx context.Context, id int, env anyrl.Env) (err error) {
+	defer func() {
+		if ctx.Err() != nil {
+			// Errors caused by cancellation are expected.
+			err = nil
+		}
+	}()
+
+	w, err := newWorker(ctx, a.Creator, id, env, a.ParamServer)
 	if err != nil {
 		return err
 	}
@@ -79,7 +91,7 @@ func (a *A3C) worker(id int, env anyrl.Env, stopChan <-chan struct{}) error {
 
 	for {
 		select {
-		case <-stopChan:
+		case <-ctx.Done():
 			return nil
 		default:
 		}
@@ -45,12 +45,14 @@ func runRollout(w *worker, maxSteps int) (*rollout, error) {
 //
 // Since this may be bootstrapped, the worker may be used
 // to run the critic on the next observation.
+// Bootstrapping is done for partial rollouts and for
+// rollouts which ended in a truncated episode.
 func (r *rollout) Advantages(w *worker, discount float64) []anyvec.Numeric {
 	c := w.Agent.Params[0].Vector.Creator()
 	ops := c.NumOps()
 
 	followingReward := c.MakeNumeric(0)
-	if !w.EnvDone {
+	if !w.EnvDone || w.EnvTruncated {
 		// Bootstrap from value function.
 		followingReward = anyvec.Sum(w.PeekCritic())
 	}
@@ -1,6 +1,8 @@
 package anya3c
 
 import (
+	"context"
+
 	"github.com/unixpickle/anynet/anyrnn"
 	"github.com/unixpickle/anyrl"
 	"github.com/unixpickle/anyvec"
@@ -8,6 +10,9 @@ import (
 
 // A worker manages the current state of a worker.
 type worker struct {
+	// Ctx is used to cancel environment calls.
+	Ctx context.Context
+
 	Creator anyvec.Creator
 
 	ID int
@@ -20,6 +25,10 @@ type worker struct {
 	EnvDone bool
 	StepIdx int
 
+	// EnvTruncated is true if EnvDone is set because the
+	// episode was truncated by a time limit.
+	EnvTruncated bool
+
 	// AgentRes is the result of applying the RNN to the
 	// current EnvObs.
 	//
@@ -42,12 +51,14 @@ type worker struct {
 // newWorker creates a worker with its own local agent.
 //
 // The worker should be reset before it is used.
-func newWorker(c anyvec.Creator, id int, env anyrl.Env, p ParamServer) (*worker, error) {
+func newWorker(ctx context.Context, c anyvec.Creator, id int, env anyrl.Env,
+	p ParamServer) (*worker, error) {
 	agent, err := p.LocalCopy()
 	if err != nil {
 		return nil, err
 	}
 	res := &worker{
+		Ctx:        ctx,
 		Creator:    c,
 		ID:         id,
 		Agent:      agent,
@@ -60,12 +71,13 @@ func newWorker(c anyvec.Creator, id int, env anyrl.Env, p ParamServer) (*worker,
 // Reset resets the environment and the RNN state.
 func (w *worker) Reset() error {
 	var err error
-	rawObs, err := w.Env.Reset()
+	rawObs, err := anyrl.EnvResetCtx(w.Ctx, w.Env)
 	if err != nil {
 		return err
 	}
 	w.EnvObs = anyvec.Make(w.Creator, rawObs)
 	w.EnvDone = false
+	w.EnvTruncated = false
 	for i, block := range w.blocks() {
 		w.AgentState[i] = block.Start(1)
 	}
@@ -100,10 +112,11 @@ func (w *worker) StepEnv() (reward float64, action anyvec.Vector, err error) {
 	action = w.Agent.ActionSpace.Sample(actorOut, 1)
 	nativeAction := w.Creator.Float64Slice(action.Data())
 	var newObs []float64
-	newObs, reward, w.EnvDone, err = w.Env.Step(nativeAction)
+	newObs, reward, w.EnvDone, err = anyrl.EnvStepCtx(w.Ctx, w.Env, nativeAction)
 	if err != nil {
 		return
 	}
+	w.EnvTruncated = w.EnvDone && anyrl.EnvTruncated(w.Env)
 	w.EnvObs = anyvec.Make(w.Creator, newObs)
 	w.RewardSum += reward
 	w.AgentRes = nil
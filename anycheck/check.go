@@ -0,0 +1,270 @@
+package anycheck
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/essentials"
+)
+
+// DefaultNumEpisodes is the default value for
+// Spec.NumEpisodes.
+const DefaultNumEpisodes = 5
+
+// DefaultMaxSteps is the default value for Spec.MaxSteps.
+const DefaultMaxSteps = 1000
+
+// Spec declares the expected properties of an
+// environment and controls how it is checked.
+type Spec struct {
+	// ObsSize is the length of observation vectors.
+	// If 0, the length of the first observation is used,
+	// and every later observation must match it.
+	ObsSize int
+
+	// ActionSize is the length of action vectors.
+	ActionSize int
+
+	// Discrete is true if actions are one-hot vectors.
+	// Otherwise, random actions are sampled from a
+	// standard normal distribution.
+	Discrete bool
+
+	// Actions, if non-nil, is a scripted sequence of
+	// actions which is used instead of random actions.
+	// Every episode uses the script from the start, and
+	// an episode is cut off when the script ends.
+	Actions [][]float64
+
+	// NumEpisodes is the number of episodes to run.
+	// If 0, DefaultNumEpisodes is used.
+	NumEpisodes int
+
+	// MaxSteps is the maximum number of steps to run in
+	// each episode.
+	// If 0, DefaultMaxSteps is used.
+	MaxSteps int
+
+	// Rand is used to sample random actions and seeds.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+}
+
+// A Violation is a problem found by a check.
+type Violation struct {
+	// Episode and Step locate the problem.
+	// Step is -1 for problems with Reset.
+	// Both are -1 for problems found outside of an
+	// episode.
+	Episode int
+	Step    int
+
+	Message string
+}
+
+// String returns a human-readable description.
+func (v *Violation) String() string {
+	if v.Episode < 0 {
+		return v.Message
+	} else if v.Step < 0 {
+		return fmt.Sprintf("episode %d: reset: %s", v.Episode, v.Message)
+	}
+	return fmt.Sprintf("episode %d: step %d: %s", v.Episode, v.Step, v.Message)
+}
+
+// Check runs an environment and returns all of the
+// violations that it finds.
+//
+// If the environment returns an error from Reset or Step,
+// the check is aborted and the error is returned along
+// with the violations found so far.
+// Errors from steps taken after an episode is done are
+// expected, so they are not treated as failures.
+//
+// The following properties are checked:
+//
+//   - Observations have a constant length.
+//   - Observations and rewards are finite.
+//   - Stepping after an episode is done does not panic
+//     and does not produce malformed observations.
+//   - If env is an anyrl.Seeder, seeding it twice with the
+//     same seed yields the same initial observation.
+//   - If env is an anyrl.GymInfoEnv, actions and
+//     observations survive a Gym conversion round trip.
+func Check(env anyrl.Env, spec *Spec) (violations []*Violation, err error) {
+	defer essentials.AddCtxTo("check env", &err)
+	c := &checker{Env: env, Spec: spec, ObsSize: spec.ObsSize}
+
+	if err := c.CheckSeeding(); err != nil {
+		return c.Violations, err
+	}
+
+	numEpisodes := spec.NumEpisodes
+	if numEpisodes == 0 {
+		numEpisodes = DefaultNumEpisodes
+	}
+	for ep := 0; ep < numEpisodes; ep++ {
+		if err := c.CheckEpisode(ep); err != nil {
+			return c.Violations, err
+		}
+	}
+	return c.Violations, nil
+}
+
+type checker struct {
+	Env     anyrl.Env
+	Spec    *Spec
+	ObsSize int
+
+	Violations []*Violation
+}
+
+func (c *checker) CheckSeeding() error {
+	if _, ok := c.Env.(anyrl.Seeder); !ok {
+		return nil
+	}
+	seed := c.int63()
+	var inits [][]float64
+	for i := 0; i < 2; i++ {
+		anyrl.SeedEnv(c.Env, seed)
+		obs, err := c.Env.Reset()
+		if err != nil {
+			return err
+		}
+		inits = append(inits, obs)
+	}
+	if !reflect.DeepEqual(inits[0], inits[1]) {
+		c.add(-1, -1, "seeding does not make Reset deterministic")
+	}
+	return nil
+}
+
+func (c *checker) CheckEpisode(ep int) error {
+	obs, err := c.Env.Reset()
+	if err != nil {
+		return err
+	}
+	c.checkObs(ep, -1, obs)
+
+	maxSteps := c.Spec.MaxSteps
+	if maxSteps == 0 {
+		maxSteps = DefaultMaxSteps
+	}
+	for t := 0; t < maxSteps; t++ {
+		action := c.action(t)
+		if action == nil {
+			return nil
+		}
+		c.checkAction(ep, t, action)
+		obs, rew, done, err := c.Env.Step(action)
+		if err != nil {
+			return err
+		}
+		c.checkObs(ep, t, obs)
+		if math.IsNaN(rew) || math.IsInf(rew, 0) {
+			c.add(ep, t, fmt.Sprintf("reward is not finite: %f", rew))
+		}
+		if done {
+			c.checkStepAfterDone(ep, t+1, action)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkStepAfterDone(ep, t int, action []float64) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.add(ep, t, fmt.Sprintf("step after done panicked: %v", r))
+		}
+	}()
+	obs, _, _, err := c.Env.Step(action)
+	if err == nil {
+		c.checkObs(ep, t, obs)
+	}
+}
+
+func (c *checker) checkObs(ep, t int, obs []float64) {
+	if c.ObsSize == 0 {
+		c.ObsSize = len(obs)
+	} else if len(obs) != c.ObsSize {
+		c.add(ep, t, fmt.Sprintf("expected observation length %d but got %d",
+			c.ObsSize, len(obs)))
+	}
+	for i, x := range obs {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			c.add(ep, t, fmt.Sprintf("observation component %d is not finite: %f",
+				i, x))
+			break
+		}
+	}
+	if gymEnv, ok := c.Env.(anyrl.GymInfoEnv); ok {
+		c.checkRoundTrip(ep, t, "observation", gymEnv.ObsSpace(), obs)
+	}
+}
+
+func (c *checker) checkAction(ep, t int, action []float64) {
+	if gymEnv, ok := c.Env.(anyrl.GymInfoEnv); ok {
+		c.checkRoundTrip(ep, t, "action", gymEnv.ActionSpace(), action)
+	}
+}
+
+func (c *checker) checkRoundTrip(ep, t int, name string, space *anyrl.GymSpace,
+	vec []float64) {
+	res, err := space.RoundTrip(vec)
+	if err != nil {
+		c.add(ep, t, fmt.Sprintf("%s round trip failed: %v", name, err))
+	} else if !reflect.DeepEqual(res, vec) {
+		c.add(ep, t, fmt.Sprintf("%s round trip changed %v to %v", name, vec, res))
+	}
+}
+
+func (c *checker) action(t int) []float64 {
+	if c.Spec.Actions != nil {
+		if t >= len(c.Spec.Actions) {
+			return nil
+		}
+		return c.Spec.Actions[t]
+	}
+	res := make([]float64, c.Spec.ActionSize)
+	if c.Spec.Discrete {
+		res[c.intn(len(res))] = 1
+	} else {
+		for i := range res {
+			res[i] = c.normFloat64()
+		}
+	}
+	return res
+}
+
+func (c *checker) add(ep, t int, msg string) {
+	c.Violations = append(c.Violations, &Violation{
+		Episode: ep,
+		Step:    t,
+		Message: msg,
+	})
+}
+
+func (c *checker) int63() int64 {
+	if c.Spec.Rand == nil {
+		return rand.Int63()
+	}
+	return c.Spec.Rand.Int63()
+}
+
+func (c *checker) intn(n int) int {
+	if c.Spec.Rand == nil {
+		return rand.Intn(n)
+	}
+	return c.Spec.Rand.Intn(n)
+}
+
+func (c *checker) normFloat64() float64 {
+	if c.Spec.Rand == nil {
+		return rand.NormFloat64()
+	}
+	return c.Spec.Rand.NormFloat64()
+}
@@ -0,0 +1,75 @@
+package anycheck
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/anyrl/anyenv"
+)
+
+func TestCheckSpecs(t *testing.T) {
+	for _, spec := range anyenv.Specs() {
+		gen := rand.New(rand.NewSource(1337))
+		violations, err := Check(spec.New(gen), &Spec{
+			ObsSize:    spec.ObsSize,
+			ActionSize: spec.ActionSize,
+			Discrete:   spec.Discrete,
+			MaxSteps:   spec.MaxSteps,
+			Rand:       gen,
+		})
+		if err != nil {
+			t.Errorf("%s: %v", spec.ID, err)
+		}
+		for _, v := range violations {
+			t.Errorf("%s: %s", spec.ID, v)
+		}
+	}
+}
+
+func TestCheckViolations(t *testing.T) {
+	env := &anyrl.MaxStepsEnv{Env: &badEnv{}, MaxSteps: 3}
+	violations, err := Check(env, &Spec{
+		ActionSize:  1,
+		NumEpisodes: 1,
+		Actions:     [][]float64{{0}, {1}, {2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 3 {
+		t.Fatalf("expected 3 violations but got %v", violations)
+	}
+	for i, step := range []int{0, 1, 3} {
+		if violations[i].Step != step {
+			t.Errorf("violation %d: expected step %d but got %d", i, step,
+				violations[i].Step)
+		}
+	}
+}
+
+// badEnv produces a NaN reward, then an observation of
+// the wrong size, and then panics after the episode.
+type badEnv struct {
+	timestep int
+}
+
+func (b *badEnv) Reset() ([]float64, error) {
+	b.timestep = 0
+	return []float64{0, 0}, nil
+}
+
+func (b *badEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	b.timestep++
+	switch b.timestep {
+	case 1:
+		return []float64{0, 0}, math.NaN(), false, nil
+	case 2:
+		return []float64{0}, 0, false, nil
+	case 3:
+		return []float64{0, 0}, 0, false, nil
+	default:
+		panic("stepped after episode")
+	}
+}
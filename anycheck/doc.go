@@ -0,0 +1,10 @@
+// Package anycheck checks that anyrl.Env implementations
+// behave correctly.
+//
+// A checker runs an environment with random or scripted
+// actions and reports every way in which the environment
+// violates the expectations declared in a Spec.
+// This includes malformed observations and rewards,
+// unsafe behavior after an episode ends, non-determinism
+// under seeding, and broken Gym space conversions.
+package anycheck
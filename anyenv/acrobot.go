@@ -0,0 +1,152 @@
+package anyenv
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Physical constants for Acrobot.
+const (
+	acrobotDt        = 0.2
+	acrobotLength1   = 1.0
+	acrobotMass1     = 1.0
+	acrobotMass2     = 1.0
+	acrobotCOMPos1   = 0.5
+	acrobotCOMPos2   = 0.5
+	acrobotMOI       = 1.0
+	acrobotMaxVel1   = 4 * math.Pi
+	acrobotMaxVel2   = 9 * math.Pi
+	acrobotGravity   = 9.8
+	acrobotStateSize = 4
+)
+
+// Acrobot is the two-link acrobot swing-up problem,
+// equivalent to Gym's Acrobot environment without a time
+// limit.
+// It uses the dynamics from the book by Sutton and Barto.
+//
+// Observations are <cos(theta1), sin(theta1), cos(theta2),
+// sin(theta2), theta1_dot, theta2_dot>.
+// Actions are one-hot vectors of length 3, corresponding
+// to torques of -1, 0, and 1.
+//
+// The agent receives a reward of -1 for every step that
+// does not reach the goal.
+type Acrobot struct {
+	// Rand is used to sample initial states.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	state   [acrobotStateSize]float64
+	running bool
+}
+
+// Seed sets Rand to a new generator with the seed.
+func (a *Acrobot) Seed(seed int64) {
+	a.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Reset resets the environment.
+func (a *Acrobot) Reset() ([]float64, error) {
+	for i := range a.state {
+		a.state[i] = uniform(a.Rand, -0.1, 0.1)
+	}
+	a.running = true
+	return a.obs(), nil
+}
+
+// Step takes a step in the environment.
+func (a *Acrobot) Step(action []float64) (obs []float64, rew float64,
+	done bool, err error) {
+	if !a.running {
+		err = errNotRunning
+		return
+	}
+	idx, err := oneHotIndex(action, 3)
+	if err != nil {
+		return
+	}
+	torque := float64(idx - 1)
+
+	ns := acrobotRK4(a.state, torque)
+	ns[0] = wrapAngle(ns[0])
+	ns[1] = wrapAngle(ns[1])
+	ns[2] = clip(ns[2], -acrobotMaxVel1, acrobotMaxVel1)
+	ns[3] = clip(ns[3], -acrobotMaxVel2, acrobotMaxVel2)
+	a.state = ns
+
+	done = -math.Cos(ns[0])-math.Cos(ns[1]+ns[0]) > 1
+	a.running = !done
+	if !done {
+		rew = -1
+	}
+	return a.obs(), rew, done, nil
+}
+
+func (a *Acrobot) obs() []float64 {
+	s := a.state
+	return []float64{
+		math.Cos(s[0]), math.Sin(s[0]),
+		math.Cos(s[1]), math.Sin(s[1]),
+		s[2], s[3],
+	}
+}
+
+// acrobotRK4 integrates the equations of motion over one
+// timestep using the fourth-order Runge-Kutta method.
+func acrobotRK4(s [acrobotStateSize]float64,
+	torque float64) [acrobotStateSize]float64 {
+	const dt = acrobotDt
+	offset := func(base, deriv [acrobotStateSize]float64,
+		scale float64) [acrobotStateSize]float64 {
+		for i, x := range deriv {
+			base[i] += x * scale
+		}
+		return base
+	}
+	k1 := acrobotDerivs(s, torque)
+	k2 := acrobotDerivs(offset(s, k1, dt/2), torque)
+	k3 := acrobotDerivs(offset(s, k2, dt/2), torque)
+	k4 := acrobotDerivs(offset(s, k3, dt), torque)
+	for i := range s {
+		s[i] += dt / 6 * (k1[i] + 2*k2[i] + 2*k3[i] + k4[i])
+	}
+	return s
+}
+
+func acrobotDerivs(s [acrobotStateSize]float64,
+	torque float64) [acrobotStateSize]float64 {
+	const (
+		m1  = acrobotMass1
+		m2  = acrobotMass2
+		l1  = acrobotLength1
+		lc1 = acrobotCOMPos1
+		lc2 = acrobotCOMPos2
+		i1  = acrobotMOI
+		i2  = acrobotMOI
+		g   = acrobotGravity
+	)
+	theta1, theta2, dtheta1, dtheta2 := s[0], s[1], s[2], s[3]
+	d1 := m1*lc1*lc1 + m2*(l1*l1+lc2*lc2+2*l1*lc2*math.Cos(theta2)) + i1 + i2
+	d2 := m2*(lc2*lc2+l1*lc2*math.Cos(theta2)) + i2
+	phi2 := m2 * lc2 * g * math.Cos(theta1+theta2-math.Pi/2)
+	phi1 := -m2*l1*lc2*dtheta2*dtheta2*math.Sin(theta2) -
+		2*m2*l1*lc2*dtheta2*dtheta1*math.Sin(theta2) +
+		(m1*lc1+m2*l1)*g*math.Cos(theta1-math.Pi/2) + phi2
+	ddtheta2 := (torque + d2/d1*phi1 -
+		m2*l1*lc2*dtheta1*dtheta1*math.Sin(theta2) - phi2) /
+		(m2*lc2*lc2 + i2 - d2*d2/d1)
+	ddtheta1 := -(d2*ddtheta2 + phi1) / d1
+	return [acrobotStateSize]float64{dtheta1, dtheta2, ddtheta1, ddtheta2}
+}
+
+// wrapAngle wraps an angle into the range [-pi, pi].
+func wrapAngle(x float64) float64 {
+	for x > math.Pi {
+		x -= 2 * math.Pi
+	}
+	for x < -math.Pi {
+		x += 2 * math.Pi
+	}
+	return x
+}
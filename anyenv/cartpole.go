@@ -0,0 +1,93 @@
+package anyenv
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Physical constants for CartPole.
+const (
+	cartPoleGravity    = 9.8
+	cartPoleMassCart   = 1.0
+	cartPoleMassPole   = 0.1
+	cartPoleTotalMass  = cartPoleMassCart + cartPoleMassPole
+	cartPoleLength     = 0.5
+	cartPolePoleMassL  = cartPoleMassPole * cartPoleLength
+	cartPoleForceMag   = 10.0
+	cartPoleTau        = 0.02
+	cartPoleXThreshold = 2.4
+	cartPoleThetaLimit = 12 * 2 * math.Pi / 360
+)
+
+// CartPole is the classic cart-pole balancing problem,
+// equivalent to Gym's CartPole environments without a
+// time limit.
+//
+// Observations are <x, x_dot, theta, theta_dot>.
+// Actions are one-hot vectors of length 2, where the
+// second component pushes the cart to the right.
+//
+// The agent receives a reward of 1 for every step,
+// including the terminal one.
+type CartPole struct {
+	// Rand is used to sample initial states.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	state   [4]float64
+	running bool
+}
+
+// Seed sets Rand to a new generator with the seed.
+func (c *CartPole) Seed(seed int64) {
+	c.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Reset resets the environment.
+func (c *CartPole) Reset() ([]float64, error) {
+	for i := range c.state {
+		c.state[i] = uniform(c.Rand, -0.05, 0.05)
+	}
+	c.running = true
+	return c.obs(), nil
+}
+
+// Step takes a step in the environment.
+func (c *CartPole) Step(action []float64) (obs []float64, rew float64,
+	done bool, err error) {
+	if !c.running {
+		err = errNotRunning
+		return
+	}
+	idx, err := oneHotIndex(action, 2)
+	if err != nil {
+		return
+	}
+
+	x, xDot, theta, thetaDot := c.state[0], c.state[1], c.state[2], c.state[3]
+	force := -cartPoleForceMag
+	if idx == 1 {
+		force = cartPoleForceMag
+	}
+	cos, sin := math.Cos(theta), math.Sin(theta)
+	temp := (force + cartPolePoleMassL*thetaDot*thetaDot*sin) / cartPoleTotalMass
+	thetaAcc := (cartPoleGravity*sin - cos*temp) /
+		(cartPoleLength * (4.0/3.0 - cartPoleMassPole*cos*cos/cartPoleTotalMass))
+	xAcc := temp - cartPolePoleMassL*thetaAcc*cos/cartPoleTotalMass
+
+	x += cartPoleTau * xDot
+	xDot += cartPoleTau * xAcc
+	theta += cartPoleTau * thetaDot
+	thetaDot += cartPoleTau * thetaAcc
+	c.state = [4]float64{x, xDot, theta, thetaDot}
+
+	done = x < -cartPoleXThreshold || x > cartPoleXThreshold ||
+		theta < -cartPoleThetaLimit || theta > cartPoleThetaLimit
+	c.running = !done
+
+	return c.obs(), 1, done, nil
+}
+
+func (c *CartPole) obs() []float64 {
+	return append([]float64{}, c.state[:]...)
+}
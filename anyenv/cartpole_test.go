@@ -0,0 +1,47 @@
+package anyenv
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCartPoleStep(t *testing.T) {
+	env := &CartPole{running: true}
+	obs, rew, done, err := env.Step([]float64{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rew != 1 || done {
+		t.Errorf("unexpected reward %f and done %v", rew, done)
+	}
+	expected := []float64{0, 0.195121951, 0, -0.292682926}
+	for i, x := range expected {
+		if math.Abs(obs[i]-x) > 1e-6 {
+			t.Errorf("expected observation %v but got %v", expected, obs)
+			break
+		}
+	}
+}
+
+func TestCartPoleTermination(t *testing.T) {
+	env := &CartPole{}
+	if _, _, _, err := env.Step([]float64{1, 0}); err == nil {
+		t.Error("expected error before reset")
+	}
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		_, _, done, err := env.Step([]float64{1, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			if _, _, _, err := env.Step([]float64{1, 0}); err == nil {
+				t.Error("expected error after done")
+			}
+			return
+		}
+	}
+	t.Error("pushing left should end the episode")
+}
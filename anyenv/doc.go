@@ -0,0 +1,11 @@
+// Package anyenv implements native anyrl.Env instances
+// for the classic control problems from OpenAI Gym.
+//
+// The physics of each environment follow the Gym
+// reference implementations, making it possible to run
+// training loops without a gym-socket-api server.
+//
+// Discrete actions are one-hot vectors, matching the
+// vectors produced by anyrl.Softmax and by
+// anyrl.Bernoulli with OneHot set.
+package anyenv
@@ -0,0 +1,127 @@
+package anyenv
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/unixpickle/anyrl"
+)
+
+// Spec describes a registered environment.
+type Spec struct {
+	// ID is the Gym ID of the environment, such as
+	// "CartPole-v0".
+	ID string
+
+	// ObsSize is the length of observation vectors.
+	ObsSize int
+
+	// ActionSize is the length of action vectors.
+	ActionSize int
+
+	// Discrete is true if actions are one-hot vectors.
+	Discrete bool
+
+	// MaxSteps is the time limit that Gym imposes on the
+	// environment.
+	MaxSteps int
+
+	newEnv func(gen *rand.Rand) anyrl.Env
+}
+
+// New creates an instance of the environment, wrapped in
+// an anyrl.MaxStepsEnv to enforce the time limit.
+//
+// The generator is used to sample initial states.
+// If it is nil, the global math/rand generator is used.
+func (s *Spec) New(gen *rand.Rand) anyrl.Env {
+	return &anyrl.MaxStepsEnv{Env: s.newEnv(gen), MaxSteps: s.MaxSteps}
+}
+
+var specs = []*Spec{
+	{
+		ID:         "CartPole-v0",
+		ObsSize:    4,
+		ActionSize: 2,
+		Discrete:   true,
+		MaxSteps:   200,
+		newEnv: func(gen *rand.Rand) anyrl.Env {
+			return &CartPole{Rand: gen}
+		},
+	},
+	{
+		ID:         "CartPole-v1",
+		ObsSize:    4,
+		ActionSize: 2,
+		Discrete:   true,
+		MaxSteps:   500,
+		newEnv: func(gen *rand.Rand) anyrl.Env {
+			return &CartPole{Rand: gen}
+		},
+	},
+	{
+		ID:         "MountainCar-v0",
+		ObsSize:    2,
+		ActionSize: 3,
+		Discrete:   true,
+		MaxSteps:   200,
+		newEnv: func(gen *rand.Rand) anyrl.Env {
+			return &MountainCar{Rand: gen}
+		},
+	},
+	{
+		ID:         "MountainCarContinuous-v0",
+		ObsSize:    2,
+		ActionSize: 1,
+		MaxSteps:   999,
+		newEnv: func(gen *rand.Rand) anyrl.Env {
+			return &MountainCarContinuous{Rand: gen}
+		},
+	},
+	{
+		ID:         "Pendulum-v0",
+		ObsSize:    3,
+		ActionSize: 1,
+		MaxSteps:   200,
+		newEnv: func(gen *rand.Rand) anyrl.Env {
+			return &Pendulum{Rand: gen}
+		},
+	},
+	{
+		ID:         "Acrobot-v1",
+		ObsSize:    6,
+		ActionSize: 3,
+		Discrete:   true,
+		MaxSteps:   500,
+		newEnv: func(gen *rand.Rand) anyrl.Env {
+			return &Acrobot{Rand: gen}
+		},
+	},
+}
+
+// Specs returns the specs for every registered
+// environment.
+func Specs() []*Spec {
+	return append([]*Spec{}, specs...)
+}
+
+// FindSpec looks up the spec for a Gym ID.
+func FindSpec(id string) (*Spec, error) {
+	for _, s := range specs {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, errors.New("unknown environment: " + id)
+}
+
+// Make creates an environment from its Gym ID.
+//
+// See Spec.New for details.
+func Make(id string, gen *rand.Rand) (anyrl.Env, error) {
+	spec, err := FindSpec(id)
+	if err != nil {
+		return nil, err
+	}
+	return spec.New(gen), nil
+}
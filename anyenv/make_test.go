@@ -0,0 +1,74 @@
+package anyenv
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyrl"
+)
+
+func TestSpecs(t *testing.T) {
+	for _, spec := range Specs() {
+		t.Run(spec.ID, func(t *testing.T) {
+			run := func() [][]float64 {
+				gen := rand.New(rand.NewSource(1337))
+				env := spec.New(gen)
+				obs, err := env.Reset()
+				if err != nil {
+					t.Fatal(err)
+				}
+				res := [][]float64{obs}
+				for i := 0; i < spec.MaxSteps; i++ {
+					action := make([]float64, spec.ActionSize)
+					if spec.Discrete {
+						action[gen.Intn(spec.ActionSize)] = 1
+					} else {
+						for j := range action {
+							action[j] = gen.NormFloat64()
+						}
+					}
+					obs, _, done, err := env.Step(action)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(obs) != spec.ObsSize {
+						t.Fatalf("expected observation size %d but got %d",
+							spec.ObsSize, len(obs))
+					}
+					res = append(res, obs)
+					if done {
+						break
+					}
+				}
+				if len(res) > spec.MaxSteps+1 {
+					t.Errorf("exceeded time limit")
+				}
+				return res
+			}
+			if !reflect.DeepEqual(run(), run()) {
+				t.Error("non-deterministic trajectory")
+			}
+		})
+	}
+}
+
+func TestSpecSeed(t *testing.T) {
+	for _, spec := range Specs() {
+		env := spec.New(nil)
+		var inits [][]float64
+		for i := 0; i < 2; i++ {
+			if !anyrl.SeedEnv(env, 1337) {
+				t.Fatalf("%s: environment is not a Seeder", spec.ID)
+			}
+			obs, err := env.Reset()
+			if err != nil {
+				t.Fatal(err)
+			}
+			inits = append(inits, obs)
+		}
+		if !reflect.DeepEqual(inits[0], inits[1]) {
+			t.Errorf("%s: seeded initial observations differ", spec.ID)
+		}
+	}
+}
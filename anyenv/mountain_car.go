@@ -0,0 +1,136 @@
+package anyenv
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Physical constants shared by the mountain car
+// environments.
+const (
+	mountainCarMinPos   = -1.2
+	mountainCarMaxPos   = 0.6
+	mountainCarMaxSpeed = 0.07
+	mountainCarGravity  = 0.0025
+)
+
+// MountainCar is the discrete mountain car problem,
+// equivalent to Gym's MountainCar environment without a
+// time limit.
+//
+// Observations are <position, velocity>.
+// Actions are one-hot vectors of length 3, corresponding
+// to pushing left, not pushing, and pushing right.
+//
+// The agent receives a reward of -1 for every step.
+type MountainCar struct {
+	// Rand is used to sample initial states.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	car mountainCarState
+}
+
+// Seed sets Rand to a new generator with the seed.
+func (m *MountainCar) Seed(seed int64) {
+	m.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Reset resets the environment.
+func (m *MountainCar) Reset() ([]float64, error) {
+	m.car.Reset(m.Rand)
+	return m.car.Obs(), nil
+}
+
+// Step takes a step in the environment.
+func (m *MountainCar) Step(action []float64) (obs []float64, rew float64,
+	done bool, err error) {
+	if !m.car.Running {
+		err = errNotRunning
+		return
+	}
+	idx, err := oneHotIndex(action, 3)
+	if err != nil {
+		return
+	}
+	m.car.Move(float64(idx-1) * 0.001)
+	done = m.car.Position >= 0.5 && m.car.Velocity >= 0
+	m.car.Running = !done
+	return m.car.Obs(), -1, done, nil
+}
+
+// MountainCarContinuous is the continuous mountain car
+// problem, equivalent to Gym's MountainCarContinuous
+// environment without a time limit.
+//
+// Observations are <position, velocity>.
+// Actions are 1-dimensional force values, which are
+// clipped to the range [-1, 1].
+//
+// The agent receives a reward of 100 for reaching the
+// goal, minus 0.1 times the squared action at each step.
+type MountainCarContinuous struct {
+	// Rand is used to sample initial states.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	car mountainCarState
+}
+
+// Seed sets Rand to a new generator with the seed.
+func (m *MountainCarContinuous) Seed(seed int64) {
+	m.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Reset resets the environment.
+func (m *MountainCarContinuous) Reset() ([]float64, error) {
+	m.car.Reset(m.Rand)
+	return m.car.Obs(), nil
+}
+
+// Step takes a step in the environment.
+func (m *MountainCarContinuous) Step(action []float64) (obs []float64,
+	rew float64, done bool, err error) {
+	if !m.car.Running {
+		err = errNotRunning
+		return
+	}
+	if len(action) != 1 {
+		err = errActionLen(1, len(action))
+		return
+	}
+	m.car.Move(clip(action[0], -1, 1) * 0.0015)
+	done = m.car.Position >= 0.45 && m.car.Velocity >= 0
+	m.car.Running = !done
+	if done {
+		rew = 100
+	}
+	rew -= action[0] * action[0] * 0.1
+	return m.car.Obs(), rew, done, nil
+}
+
+type mountainCarState struct {
+	Position float64
+	Velocity float64
+	Running  bool
+}
+
+func (m *mountainCarState) Reset(gen *rand.Rand) {
+	m.Position = uniform(gen, -0.6, -0.4)
+	m.Velocity = 0
+	m.Running = true
+}
+
+func (m *mountainCarState) Move(force float64) {
+	m.Velocity += force - mountainCarGravity*math.Cos(3*m.Position)
+	m.Velocity = clip(m.Velocity, -mountainCarMaxSpeed, mountainCarMaxSpeed)
+	m.Position += m.Velocity
+	m.Position = clip(m.Position, mountainCarMinPos, mountainCarMaxPos)
+	if m.Position == mountainCarMinPos && m.Velocity < 0 {
+		m.Velocity = 0
+	}
+}
+
+func (m *mountainCarState) Obs() []float64 {
+	return []float64{m.Position, m.Velocity}
+}
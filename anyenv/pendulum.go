@@ -0,0 +1,86 @@
+package anyenv
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Physical constants for Pendulum.
+const (
+	pendulumMaxSpeed  = 8.0
+	pendulumMaxTorque = 2.0
+	pendulumDt        = 0.05
+	pendulumGravity   = 10.0
+	pendulumMass      = 1.0
+	pendulumLength    = 1.0
+)
+
+// Pendulum is the inverted pendulum swing-up problem,
+// equivalent to Gym's Pendulum environment without a
+// time limit.
+//
+// Observations are <cos(theta), sin(theta), theta_dot>.
+// Actions are 1-dimensional torques, which are clipped to
+// the range [-2, 2].
+//
+// Episodes never terminate on their own, so Pendulum
+// should be wrapped in an anyrl.MaxStepsEnv.
+type Pendulum struct {
+	// Rand is used to sample initial states.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	theta    float64
+	thetaDot float64
+	running  bool
+}
+
+// Seed sets Rand to a new generator with the seed.
+func (p *Pendulum) Seed(seed int64) {
+	p.Rand = rand.New(rand.NewSource(seed))
+}
+
+// Reset resets the environment.
+func (p *Pendulum) Reset() ([]float64, error) {
+	p.theta = uniform(p.Rand, -math.Pi, math.Pi)
+	p.thetaDot = uniform(p.Rand, -1, 1)
+	p.running = true
+	return p.obs(), nil
+}
+
+// Step takes a step in the environment.
+func (p *Pendulum) Step(action []float64) (obs []float64, rew float64,
+	done bool, err error) {
+	if !p.running {
+		err = errNotRunning
+		return
+	}
+	if len(action) != 1 {
+		err = errActionLen(1, len(action))
+		return
+	}
+	u := clip(action[0], -pendulumMaxTorque, pendulumMaxTorque)
+	normTheta := angleNormalize(p.theta)
+	rew = -(normTheta*normTheta + 0.1*p.thetaDot*p.thetaDot + 0.001*u*u)
+
+	const g, m, l = pendulumGravity, pendulumMass, pendulumLength
+	newThetaDot := p.thetaDot + (-3*g/(2*l)*math.Sin(p.theta+math.Pi)+
+		3/(m*l*l)*u)*pendulumDt
+	p.theta += newThetaDot * pendulumDt
+	p.thetaDot = clip(newThetaDot, -pendulumMaxSpeed, pendulumMaxSpeed)
+
+	return p.obs(), rew, false, nil
+}
+
+func (p *Pendulum) obs() []float64 {
+	return []float64{math.Cos(p.theta), math.Sin(p.theta), p.thetaDot}
+}
+
+// angleNormalize maps an angle into [-pi, pi).
+func angleNormalize(x float64) float64 {
+	mod := math.Mod(x+math.Pi, 2*math.Pi)
+	if mod < 0 {
+		mod += 2 * math.Pi
+	}
+	return mod - math.Pi
+}
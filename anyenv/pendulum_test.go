@@ -0,0 +1,31 @@
+package anyenv
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAngleNormalize(t *testing.T) {
+	inputs := []float64{0, 1, -1, math.Pi, 3 * math.Pi / 2, -5 * math.Pi / 2}
+	expected := []float64{0, 1, -1, -math.Pi, -math.Pi / 2, -math.Pi / 2}
+	for i, in := range inputs {
+		if actual := angleNormalize(in); math.Abs(actual-expected[i]) > 1e-8 {
+			t.Errorf("input %f: expected %f but got %f", in, expected[i], actual)
+		}
+	}
+}
+
+func TestPendulumReward(t *testing.T) {
+	env := &Pendulum{theta: 0.5, thetaDot: -1, running: true}
+	_, rew, done, err := env.Step([]float64{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := -(0.25 + 0.1 + 0.001*4)
+	if math.Abs(rew-expected) > 1e-8 {
+		t.Errorf("expected reward %f but got %f", expected, rew)
+	}
+	if done {
+		t.Error("pendulum should never be done")
+	}
+}
@@ -0,0 +1,44 @@
+package anyenv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+var errNotRunning = errors.New("step: environment must be reset")
+
+// oneHotIndex finds the index of the first non-zero
+// value in a one-hot action vector.
+func oneHotIndex(action []float64, n int) (int, error) {
+	if len(action) != n {
+		return 0, errActionLen(n, len(action))
+	}
+	for i, x := range action {
+		if x != 0 {
+			return i, nil
+		}
+	}
+	return 0, errors.New("no one-hot value is set")
+}
+
+func errActionLen(expected, actual int) error {
+	return fmt.Errorf("expected action of length %d but got %d", expected, actual)
+}
+
+// uniform samples from [min, max) using gen or, if gen is
+// nil, the global generator.
+func uniform(gen *rand.Rand, min, max float64) float64 {
+	var x float64
+	if gen == nil {
+		x = rand.Float64()
+	} else {
+		x = gen.Float64()
+	}
+	return min + x*(max-min)
+}
+
+func clip(x, min, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
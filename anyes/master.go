@@ -41,6 +41,10 @@ type Master struct {
 	// It is referred to as alpha in the original paper.
 	StepSize float64
 
+	// Rand is used to generate rollout seeds.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
 	// SlaveError is called if a Slave produces an error
 	// during a Run or Update call.
 	//
@@ -141,7 +145,7 @@ func (m *Master) Rollouts(stop *StopConds, n int) (rollouts []*Rollout, err erro
 
 	jobs := make(chan *scaleSeed, n*2)
 	for i := 0; i < n; i++ {
-		seed := rand.Int63()
+		seed := m.randSeed()
 		for _, scale := range []float64{-1, 1} {
 			jobs <- &scaleSeed{Scale: scale * m.NoiseStddev, Seed: seed}
 		}
@@ -298,6 +302,13 @@ func (m *Master) Update(r []*Rollout) (err error) {
 	return <-errChan
 }
 
+func (m *Master) randSeed() int64 {
+	if m.Rand == nil {
+		return rand.Int63()
+	}
+	return m.Rand.Int63()
+}
+
 // assignJobs assigns pending jobs to idle slaves.
 // It automatically changes the slaves' working status.
 func (m *Master) assignJobs(jobs chan *scaleSeed) []*jobAssignment {
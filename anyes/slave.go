@@ -1,8 +1,10 @@
 package anyes
 
 import (
+	"context"
 	"time"
 
+	"github.com/unixpickle/anydiff/anyseq"
 	"github.com/unixpickle/anynet/anyrnn"
 	"github.com/unixpickle/anyrl"
 	"github.com/unixpickle/anyvec"
@@ -17,6 +19,12 @@ import (
 type StopConds struct {
 	// MaxTime is the maximum time for which a rollout
 	// should be run.
+	//
+	// The time limit is checked between steps, so an
+	// environment call which is running when the time runs
+	// out is allowed to finish.
+	// To detect hung environments, use
+	// AnynetSlave.EnvTimeout or AnynetSlave.RunCtx.
 	MaxTime time.Duration
 
 	// MaxSteps is the maximum number of steps to take
@@ -76,13 +84,36 @@ type AnynetSlave struct {
 	Policy  anyrnn.Block
 	Env     anyrl.Env
 
+	// BatchEnv, if non-nil, is used instead of Env.
+	//
+	// Every environment in the batch is run with the same
+	// parameters, and the resulting Rollout reports the
+	// mean reward and the total number of steps.
+	BatchEnv anyrl.BatchEnv
+
 	// Sampler, if non-nil, is applied to Policy outputs
 	// right before they are fed to the environment.
 	Sampler anyrl.Sampler
 
+	// UseMode, if true, indicates that the mode of the
+	// Sampler should be used instead of a sample.
+	// In this case, Sampler must be an anyrl.Moder.
+	UseMode bool
+
 	// NoiseGroup is used to generate noise.
 	// If it is nil, Init sets it.
 	NoiseGroup *NoiseGroup
+
+	// EnvTimeout, if non-zero, is the maximum time for a
+	// single reset or step of the environment.
+	// If it is exceeded, Run fails with
+	// anyrl.ErrEnvTimeout.
+	//
+	// Unless the environment is an anyrl.CtxEnv (or an
+	// anyrl.CtxBatchEnv), the timed out call keeps running
+	// in the background, so the environment should not be
+	// used again.
+	EnvTimeout time.Duration
 }
 
 // Init updates the parameters and initializes the noise
@@ -102,9 +133,23 @@ func (a *AnynetSlave) Init(data []byte, seed int64, size int) (err error) {
 }
 
 // Run executes an environment rollout.
-func (a *AnynetSlave) Run(stop *StopConds, scale float64, seed int64) (r *Rollout,
-	err error) {
-	defer essentials.AddCtxTo("run AnynetSlave", &err)
+func (a *AnynetSlave) Run(stop *StopConds, scale float64, seed int64) (*Rollout, error) {
+	return a.RunCtx(context.Background(), stop, scale, seed)
+}
+
+// RunCtx is like Run, but environment calls are stopped
+// early if the context is done.
+//
+// If the context's deadline is exceeded (or if a call
+// exceeds EnvTimeout), anyrl.ErrEnvTimeout is returned.
+// If the context is cancelled, its error is returned.
+func (a *AnynetSlave) RunCtx(ctx context.Context, stop *StopConds, scale float64,
+	seed int64) (r *Rollout, err error) {
+	defer func() {
+		if err != nil && err != anyrl.ErrEnvTimeout && err != ctx.Err() {
+			err = essentials.AddCtx("run AnynetSlave", err)
+		}
+	}()
 
 	oldData, err := a.Params.Data()
 	if err != nil {
@@ -123,38 +168,60 @@ func (a *AnynetSlave) Run(stop *StopConds, scale float64, seed int64) (r *Rollou
 		Seed:      seed,
 		EarlyStop: true,
 	}
-	obs, err := a.Env.Reset()
+	env := a.BatchEnv
+	if env == nil {
+		env = anyrl.EnvBatch(a.Env)
+	}
+
+	callCtx, cancel := a.envCtx(ctx)
+	obs, err := anyrl.BatchResetCtx(callCtx, env, a.Creator)
+	cancel()
 	if err != nil {
 		return
 	}
-	state := a.Policy.Start(1)
 	var timeout <-chan time.Time
 	if stop.MaxTime != 0 {
 		timeout = time.After(stop.MaxTime)
 	}
-	for r.Steps < stop.MaxSteps || stop.MaxSteps == 0 {
+	var totalReward float64
+	defer func() {
+		r.Reward = totalReward / float64(env.NumEnvs())
+	}()
+	state := a.Policy.Start(env.NumEnvs())
+	for numSteps := 0; numSteps < stop.MaxSteps || stop.MaxSteps == 0; numSteps++ {
 		select {
 		case <-timeout:
 			return
 		default:
 		}
 
-		out := a.Policy.Step(state, anyvec.Make(a.Creator, obs))
+		if obs.NumPresent() < state.Present().NumPresent() {
+			state = state.Reduce(obs.Present)
+		}
+		out := a.Policy.Step(state, obs.Packed)
 		state = out.State()
 		action := out.Output()
 		if a.Sampler != nil {
-			action = a.Sampler.Sample(action, 1)
+			if a.UseMode {
+				action = a.Sampler.(anyrl.Moder).Mode(action, obs.NumPresent())
+			} else {
+				action = a.Sampler.Sample(action, obs.NumPresent())
+			}
 		}
 
-		var rew float64
-		var done bool
-		obs, rew, done, err = a.Env.Step(a.Creator.Float64Slice(action.Data()))
+		var rews []float64
+		r.Steps += obs.NumPresent()
+		actionBatch := &anyseq.Batch{Packed: action, Present: obs.Present}
+		callCtx, cancel := a.envCtx(ctx)
+		obs, rews, _, err = anyrl.BatchStepCtx(callCtx, env, actionBatch)
+		cancel()
 		if err != nil {
 			return
 		}
-		r.Reward += rew
-		r.Steps++
-		if done {
+		for _, rew := range rews {
+			totalReward += rew
+		}
+		if obs.NumPresent() == 0 {
 			r.EarlyStop = false
 			return
 		}
@@ -162,6 +229,14 @@ func (a *AnynetSlave) Run(stop *StopConds, scale float64, seed int64) (r *Rollou
 	return
 }
 
+// envCtx creates a context for a single environment call.
+func (a *AnynetSlave) envCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.EnvTimeout == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, a.EnvTimeout)
+}
+
 // Update updates the parameters by re-generating the
 // mutations and adding them.
 func (a *AnynetSlave) Update(scales []float64, seeds []int64) (Checksum, error) {
@@ -0,0 +1,87 @@
+package anyes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/anyvec/anyvec32"
+)
+
+func TestAnynetSlaveMaxTime(t *testing.T) {
+	slave := newSlowSlave(t, time.Millisecond*50)
+
+	// The first step finishes after the time limit, which
+	// should stop the rollout gracefully.
+	rollout, err := slave.Run(&StopConds{MaxTime: time.Millisecond * 10}, 1, 123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rollout.EarlyStop {
+		t.Error("expected early stop")
+	}
+	if rollout.Steps != 1 || rollout.Reward != 1 {
+		t.Errorf("unexpected rollout: %+v", rollout)
+	}
+}
+
+func TestAnynetSlaveEnvTimeout(t *testing.T) {
+	slave := newSlowSlave(t, time.Second)
+	slave.EnvTimeout = time.Millisecond * 10
+	_, err := slave.Run(&StopConds{MaxTime: time.Millisecond * 10}, 1, 123)
+	if err != anyrl.ErrEnvTimeout {
+		t.Errorf("expected ErrEnvTimeout but got %v", err)
+	}
+}
+
+func TestAnynetSlaveRunCtx(t *testing.T) {
+	slave := newSlowSlave(t, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := slave.RunCtx(ctx, &StopConds{}, 1, 123); err != anyrl.ErrEnvTimeout {
+		t.Errorf("expected ErrEnvTimeout but got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := slave.RunCtx(ctx, &StopConds{}, 1, 123); err != context.Canceled {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+}
+
+func newSlowSlave(t *testing.T, delay time.Duration) *AnynetSlave {
+	c := anyvec32.DefaultCreator{}
+	block := anyrnn.NewLSTM(c, 1, 1)
+	params := &AnynetParams{Params: block.Parameters()}
+	slave := &AnynetSlave{
+		Creator: c,
+		Params:  params,
+		Policy:  block,
+		Env:     &slowEnv{Delay: delay},
+	}
+	data, err := params.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := slave.Init(data, 1337, 10000); err != nil {
+		t.Fatal(err)
+	}
+	return slave
+}
+
+// slowEnv is an endless environment which takes some time
+// to step.
+type slowEnv struct {
+	Delay time.Duration
+}
+
+func (s *slowEnv) Reset() ([]float64, error) {
+	return []float64{0}, nil
+}
+
+func (s *slowEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	time.Sleep(s.Delay)
+	return []float64{0}, 1, false, nil
+}
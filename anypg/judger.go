@@ -20,14 +20,46 @@ type ActionJudger interface {
 	JudgeActions(rollouts *anyrl.RolloutSet) anyrl.Rewards
 }
 
+// ChunkJudger is an ActionJudger for the windows produced
+// by an anyrl.Chunker.
+//
+// The actions are judged in the context of the full
+// episodes using Judger, and then the judgements are
+// split into windows.
+// Burn-in timesteps are given judgements of zero, so they
+// do not contribute to policy gradients.
+//
+// The RolloutSet passed to JudgeActions is ignored, since
+// it should always be Chunks.Rollouts.
+type ChunkJudger struct {
+	Judger ActionJudger
+	Chunks *anyrl.Chunks
+}
+
+// JudgeActions judges the full episodes and splits the
+// judgements into windows.
+func (c *ChunkJudger) JudgeActions(r *anyrl.RolloutSet) anyrl.Rewards {
+	return c.Chunks.SplitRewards(c.Judger.JudgeActions(c.Chunks.Full))
+}
+
 // QJudger is an ActionJudger which judges the goodness of
 // an action by that action's sampled Q-value.
+//
+// Truncated episodes are bootstrapped with the value of
+// their final observation if ValueFunc is set.
+// Otherwise, rewards after a truncation are treated as
+// zero.
 type QJudger struct {
 	// Discount is the reward discount factor.
 	//
 	// If 0, no discount is used.
 	Discount float64
 
+	// ValueFunc, if non-nil, is used to estimate the value
+	// of the final observation of truncated episodes.
+	// It is called like GAEJudger.ValueFunc.
+	ValueFunc func(inputs lazyseq.Rereader) <-chan *anyseq.Batch
+
 	// Normalize, if true, indicates that the resulting
 	// Q-values should be statistically normalized.
 	Normalize bool
@@ -45,10 +77,19 @@ type QJudger struct {
 // is replaced with the sum of all the rewards from that
 // timestep to the end of the episode.
 func (q *QJudger) JudgeActions(r *anyrl.RolloutSet) anyrl.Rewards {
+	var values [][]float64
+	if q.ValueFunc != nil && r.Truncated != nil {
+		values = estimateValues(q.ValueFunc, lazyseq.TapeRereader(r.BootstrapInputs()),
+			len(r.Rewards))
+	}
+
 	var res anyrl.Rewards
-	for _, seq := range r.Rewards {
+	for i, seq := range r.Rewards {
 		newSeq := make([]float64, len(seq))
 		var sum float64
+		if values != nil && r.EpisodeTruncated(i) {
+			sum = values[i][len(seq)]
+		}
 		for t := len(seq) - 1; t >= 0; t-- {
 			if q.Discount != 0 {
 				sum *= q.Discount
@@ -167,20 +208,17 @@ type GAEJudger struct {
 }
 
 // JudgeActions computes generalized advantage estimates.
+//
+// For truncated episodes, the value of the final
+// observation is used to bootstrap the estimates.
+// All other episodes are assumed to end in terminal
+// states with no future value.
 func (g *GAEJudger) JudgeActions(r *anyrl.RolloutSet) anyrl.Rewards {
 	input := lazyseq.TapeRereader(r.Inputs)
-	criticOut := g.ValueFunc(input)
-
-	estimatedValues := make([][]float64, len(r.Rewards))
-	for outBatch := range criticOut {
-		comps := vectorToComponents(outBatch.Packed)
-		for i, pres := range outBatch.Present {
-			if pres {
-				estimatedValues[i] = append(estimatedValues[i], comps[0])
-				comps = comps[1:]
-			}
-		}
+	if r.Truncated != nil {
+		input = lazyseq.TapeRereader(r.BootstrapInputs())
 	}
+	estimatedValues := estimateValues(g.ValueFunc, input, len(r.Rewards))
 
 	var res [][]float64
 	for i, rewSeq := range r.Rewards {
@@ -189,7 +227,7 @@ func (g *GAEJudger) JudgeActions(r *anyrl.RolloutSet) anyrl.Rewards {
 		var accumulation float64
 		for t := len(rewSeq) - 1; t >= 0; t-- {
 			delta := rewSeq[t] - valSeq[t]
-			if t+1 < len(rewSeq) {
+			if t+1 < len(valSeq) {
 				delta += g.Discount * valSeq[t+1]
 			}
 			accumulation *= g.Discount * g.Lambda
@@ -202,6 +240,23 @@ func (g *GAEJudger) JudgeActions(r *anyrl.RolloutSet) anyrl.Rewards {
 	return anyrl.Rewards(res)
 }
 
+// estimateValues runs a value function and gathers the
+// resulting values into per-sequence slices.
+func estimateValues(valueFunc func(inputs lazyseq.Rereader) <-chan *anyseq.Batch,
+	inputs lazyseq.Rereader, numSeqs int) [][]float64 {
+	res := make([][]float64, numSeqs)
+	for outBatch := range valueFunc(inputs) {
+		comps := vectorToComponents(outBatch.Packed)
+		for i, pres := range outBatch.Present {
+			if pres {
+				res[i] = append(res[i], comps[0])
+				comps = comps[1:]
+			}
+		}
+	}
+	return res
+}
+
 func flattenRewards(r anyrl.Rewards) []float64 {
 	var values []float64
 	for _, seq := range r {
@@ -89,6 +89,43 @@ func TestGAEJudgerNoBias(t *testing.T) {
 	testRewardsEquiv(t, actual, expected)
 }
 
+func TestGAEJudgerTruncated(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	rollouts := rolloutsForTest(c)
+	rollouts.Truncated = []bool{true, false, false}
+	rollouts.FinalObs = [][]float64{{1, 2, 3}, nil, nil}
+
+	judger := &GAEJudger{
+		ValueFunc: func(inputs lazyseq.Rereader) <-chan *anyseq.Batch {
+			res := make(chan *anyseq.Batch, 1)
+			go func() {
+				for in := range inputs.Forward() {
+					ones := in.Packed.Creator().MakeVector(in.NumPresent())
+					ones.AddScalar(ones.Creator().MakeNumeric(1))
+					res <- &anyseq.Batch{Packed: ones, Present: in.Present}
+				}
+				close(res)
+			}()
+			return res
+		},
+		Discount: 0.9,
+		Lambda:   1,
+	}
+
+	actual := judger.JudgeActions(rollouts)
+	expected := (&QJudger{Discount: 0.9}).JudgeActions(rollouts)
+	for i, seq := range expected {
+		for t := range seq {
+			seq[t] -= 1
+			if rollouts.Truncated[i] {
+				seq[t] += math.Pow(0.9, float64(len(seq)-t))
+			}
+		}
+	}
+
+	testRewardsEquiv(t, actual, expected)
+}
+
 func TestTotalJudger(t *testing.T) {
 	rewards := [][]float64{
 		{1, 2, 3, 1},
@@ -111,6 +148,28 @@ func TestTotalJudger(t *testing.T) {
 	testRewardsEquiv(t, actual, expected)
 }
 
+func TestChunkJudger(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	full := anyrl.TrajectoryRolloutSet(c, []*anyrl.Trajectory{
+		{
+			Observations: [][]float64{{0}, {1}, {2}},
+			Actions:      [][]float64{{0}, {1}, {2}},
+			Rewards:      []float64{1, 0.5, 2},
+			Done:         true,
+		},
+	})
+	chunks := (&anyrl.Chunker{WindowSize: 2, BurnIn: 1}).Chunk(full)
+	judger := &ChunkJudger{Judger: &QJudger{}, Chunks: chunks}
+
+	actual := judger.JudgeActions(chunks.Rollouts)
+	expected := [][]float64{
+		{3.5, 2.5},
+		{0, 2},
+	}
+
+	testRewardsEquiv(t, actual, expected)
+}
+
 func testRewardsEquiv(t *testing.T, actual, expected anyrl.Rewards) {
 	if len(actual) != len(expected) {
 		t.Errorf("expected %d sequences but got %d", len(expected), len(actual))
@@ -58,6 +58,7 @@ type NaturalPG struct {
 	// For an example of something to use, see FracReducer.
 	//
 	// If nil, all rollouts are used.
+	// RunChunks always uses all the windows.
 	Reduce func(in *anyrl.RolloutSet) *anyrl.RolloutSet
 
 	// Regularizer is used to regularize the action space.
@@ -68,11 +69,21 @@ type NaturalPG struct {
 
 // Run computes the natural gradient for the rollouts.
 func (n *NaturalPG) Run(r *anyrl.RolloutSet) anydiff.Grad {
-	return n.run(r).Grad
+	return n.run(r, nil).Grad
 }
 
-func (n *NaturalPG) run(r *anyrl.RolloutSet) *naturalPGRes {
-	res := &naturalPGRes{ReducedRollouts: r}
+// RunChunks is like Run, but for windows produced by an
+// anyrl.Chunker.
+//
+// See PG.RunChunks for details.
+// Burn-in timesteps are also excluded from the KL
+// divergence used to compute the natural gradient.
+func (n *NaturalPG) RunChunks(chunks *anyrl.Chunks) anydiff.Grad {
+	return n.run(chunks.Rollouts, chunks).Grad
+}
+
+func (n *NaturalPG) run(r *anyrl.RolloutSet, chunks *anyrl.Chunks) *naturalPGRes {
+	res := &naturalPGRes{ReducedRollouts: r, Chunks: chunks}
 	pg := &PG{
 		Policy: func(in lazyseq.Rereader) lazyseq.Rereader {
 			res.PolicyOut = lazyseq.MakeReuser(n.apply(in, n.Policy))
@@ -84,7 +95,12 @@ func (n *NaturalPG) run(r *anyrl.RolloutSet) *naturalPGRes {
 		ActionJudger: n.ActionJudger,
 		Regularizer:  n.Regularizer,
 	}
-	res.Grad = pg.Run(r)
+	if chunks == nil {
+		res.Grad = pg.Run(r)
+	} else {
+		res.Mask = newWindowMask(chunks)
+		res.Grad = pg.RunChunks(chunks)
+	}
 
 	// We check for an all-zero gradient because that is
 	// a fairly common case (if all rollouts were optimal,
@@ -94,19 +110,19 @@ func (n *NaturalPG) run(r *anyrl.RolloutSet) *naturalPGRes {
 		return res
 	}
 
-	if n.Reduce != nil {
+	if n.Reduce != nil && chunks == nil {
 		res.ReducedRollouts = n.Reduce(r)
 		in := lazyseq.TapeRereader(res.ReducedRollouts.Inputs)
 		res.ReducedOut = lazyseq.MakeReuser(n.apply(in, n.Policy))
 	}
 
-	n.conjugateGradients(res.ReducedRollouts, res.ReducedOut, res.Grad)
+	n.conjugateGradients(res.ReducedRollouts, res.ReducedOut, res.Grad, res.Mask)
 
 	return res
 }
 
 func (n *NaturalPG) conjugateGradients(r *anyrl.RolloutSet, policyOuts lazyseq.Reuser,
-	grad anydiff.Grad) {
+	grad anydiff.Grad, mask *windowMask) {
 	c := r.Creator()
 	ops := c.NumOps()
 
@@ -129,7 +145,7 @@ func (n *NaturalPG) conjugateGradients(r *anyrl.RolloutSet, policyOuts lazyseq.R
 	for i := 0; i < n.iters(); i++ {
 		// A*p
 		policyOuts.Reuse()
-		appliedProj := n.applyFisher(r, proj, policyOuts)
+		appliedProj := n.applyFisher(r, proj, policyOuts, mask)
 
 		// (r dot r) / (p dot A*p)
 		alpha := ops.Div(residualMag, dotGrad(proj, appliedProj))
@@ -159,7 +175,7 @@ func (n *NaturalPG) conjugateGradients(r *anyrl.RolloutSet, policyOuts lazyseq.R
 }
 
 func (n *NaturalPG) applyFisher(r *anyrl.RolloutSet, grad anydiff.Grad,
-	oldOuts lazyseq.Rereader) anydiff.Grad {
+	oldOuts lazyseq.Rereader, mask *windowMask) anydiff.Grad {
 	c := &anyfwd.Creator{
 		ValueCreator: r.Creator(),
 		GradSize:     1,
@@ -172,13 +188,24 @@ func (n *NaturalPG) applyFisher(r *anyrl.RolloutSet, grad anydiff.Grad,
 		Regular:      oldOuts,
 		FwdToRegular: paramMap,
 	}
-	klSeq := lazyseq.Map(outSeq, func(v anydiff.Res, num int) anydiff.Res {
-		zeroGrad := c.ValueCreator.MakeVector(v.Output().Len())
-		constVec := v.Output().Copy()
+	var fwdMask *windowMask
+	if mask != nil {
+		fwdMask = &windowMask{
+			Mask:        &makeFwdTape{Tape: mask.Mask, creator: c},
+			NumUnmasked: mask.NumUnmasked,
+		}
+	}
+	klSeq := lazyseq.MapN(func(num int, v ...anydiff.Res) anydiff.Res {
+		zeroGrad := c.ValueCreator.MakeVector(v[0].Output().Len())
+		constVec := v[0].Output().Copy()
 		constVec.(*anyfwd.Vector).Jacobian[0].Set(zeroGrad)
-		return n.ActionSpace.KL(anydiff.NewConst(constVec), v, num)
-	})
-	kl := lazyseq.Mean(klSeq)
+		kl := n.ActionSpace.KL(anydiff.NewConst(constVec), v[0], num)
+		if len(v) > 1 {
+			kl = anydiff.Mul(kl, v[1])
+		}
+		return kl
+	}, fwdMask.Seqs(outSeq)...)
+	kl := fwdMask.Mean(r, klSeq)
 
 	newGrad := anydiff.Grad{}
 	for newParam, oldParam := range paramMap {
@@ -251,6 +278,10 @@ type naturalPGRes struct {
 	// Always non-nil, but may equal the unreduced version.
 	ReducedOut      lazyseq.Reuser
 	ReducedRollouts *anyrl.RolloutSet
+
+	// Set when running on windows from a Chunker.
+	Chunks *anyrl.Chunks
+	Mask   *windowMask
 }
 
 func (n *naturalPGRes) Creator() anyvec.Creator {
@@ -41,11 +41,11 @@ func TestFisherDeterministic(t *testing.T) {
 	outSeq := lazyseq.MakeReuser(npg.apply(lazyseq.TapeRereader(r.Inputs),
 		npg.Policy))
 
-	grad1 := npg.applyFisher(r, inGrad, outSeq)
+	grad1 := npg.applyFisher(r, inGrad, outSeq, nil)
 	mag1 := dotGrad(grad1, grad1).(float64)
 	for i := 0; i < 1000; i++ {
 		outSeq.Reuse()
-		grad2 := npg.applyFisher(r, inGrad, outSeq)
+		grad2 := npg.applyFisher(r, inGrad, outSeq, nil)
 		mag2 := dotGrad(grad2, grad2).(float64)
 		correlation := dotGrad(grad1, grad2).(float64) / math.Sqrt(mag1*mag2)
 		if correlation < 1-1e-3 {
@@ -88,7 +88,7 @@ func TestFisher(t *testing.T) {
 	close(writer)
 	outSeq.Reuse()
 
-	applied := npg.applyFisher(r, inGrad, outSeq)
+	applied := npg.applyFisher(r, inGrad, outSeq, nil)
 	actualOutput := 0.5 * dotGrad(inGrad, applied).(float64)
 
 	inGrad.AddToVars()
@@ -136,11 +136,11 @@ func TestConjugateGradients(t *testing.T) {
 	solvedGrad := copyGrad(inGrad)
 
 	outSeq := lazyseq.MakeReuser(npg.apply(lazyseq.TapeRereader(r.Inputs), npg.Policy))
-	npg.conjugateGradients(r, outSeq, solvedGrad)
+	npg.conjugateGradients(r, outSeq, solvedGrad, nil)
 
 	// Check that F*solvedGrad = inGrad.
 	outSeq.Reuse()
-	actualProduct := npg.applyFisher(r, solvedGrad, outSeq)
+	actualProduct := npg.applyFisher(r, solvedGrad, outSeq, nil)
 	expectedProduct := inGrad
 
 	if len(actualProduct) != len(expectedProduct) {
@@ -159,6 +159,32 @@ func TestConjugateGradients(t *testing.T) {
 	}
 }
 
+func TestNaturalPGRunChunks(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	r := rolloutsForTest(c)
+
+	block := &anyrnn.LayerBlock{
+		Layer: anynet.Net{
+			anynet.NewFC(c, 3, 2),
+			anynet.Tanh,
+			anynet.NewFC(c, 2, 2),
+		},
+	}
+	npg := &NaturalPG{
+		Policy:      block,
+		Params:      block.Parameters(),
+		ActionSpace: anyrl.Softmax{},
+		Iters:       14,
+	}
+
+	// With a feed-forward policy, the Fisher matrix should
+	// not depend on the burn-in timesteps.
+	expected := npg.Run(r)
+	chunks := (&anyrl.Chunker{WindowSize: 2, BurnIn: 1}).Chunk(r)
+	actual := npg.RunChunks(chunks)
+	testGradsEquiv(t, expected, actual)
+}
+
 func BenchmarkFisher(b *testing.B) {
 	c := anyvec64.DefaultCreator{}
 	r := rolloutsForTest(c)
@@ -190,7 +216,7 @@ func BenchmarkFisher(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		npg.applyFisher(r, inGrad, outSeq)
+		npg.applyFisher(r, inGrad, outSeq, nil)
 	}
 }
 
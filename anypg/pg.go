@@ -31,6 +31,25 @@ type PG struct {
 
 // Run performs policy gradients on the rollouts.
 func (p *PG) Run(r *anyrl.RolloutSet) anydiff.Grad {
+	return p.run(r, p.actionJudger().JudgeActions(r), nil)
+}
+
+// RunChunks is like Run, but for windows produced by an
+// anyrl.Chunker.
+//
+// The actions are judged in the context of the full
+// episodes, as with a ChunkJudger, so ActionJudger should
+// not be a ChunkJudger itself.
+// Burn-in timesteps are excluded from every term, and the
+// objective is averaged over the window timesteps only.
+func (p *PG) RunChunks(chunks *anyrl.Chunks) anydiff.Grad {
+	judger := &ChunkJudger{Judger: p.actionJudger(), Chunks: chunks}
+	return p.run(chunks.Rollouts, judger.JudgeActions(chunks.Rollouts),
+		newWindowMask(chunks))
+}
+
+func (p *PG) run(r *anyrl.RolloutSet, judgements anyrl.Rewards,
+	mask *windowMask) anydiff.Grad {
 	grad := anydiff.NewGrad(p.Params...)
 	if len(grad) == 0 {
 		return grad
@@ -40,7 +59,7 @@ func (p *PG) Run(r *anyrl.RolloutSet) anydiff.Grad {
 	policyOut := p.Policy(lazyseq.TapeRereader(r.Inputs))
 
 	selectedOuts := lazyseq.TapeRereader(r.Actions)
-	rewards := lazyseq.TapeRereader(p.actionJudger().JudgeActions(r).Tape(c))
+	rewards := lazyseq.TapeRereader(judgements.Tape(c))
 
 	scores := lazyseq.MapN(func(n int, v ...anydiff.Res) anydiff.Res {
 		actionParams := v[0]
@@ -51,10 +70,13 @@ func (p *PG) Run(r *anyrl.RolloutSet) anydiff.Grad {
 		if p.Regularizer != nil {
 			cost = anydiff.Add(cost, p.Regularizer.Regularize(actionParams, n))
 		}
+		if len(v) > 3 {
+			cost = anydiff.Mul(cost, v[3])
+		}
 		return cost
-	}, policyOut, selectedOuts, rewards)
+	}, mask.Seqs(policyOut, selectedOuts, rewards)...)
 
-	score := lazyseq.Mean(scores)
+	score := mask.Mean(r, scores)
 	one := c.MakeVector(1)
 	one.AddScalar(c.MakeNumeric(1))
 	score.Propagate(one, grad)
@@ -62,6 +84,42 @@ func (p *PG) Run(r *anyrl.RolloutSet) anydiff.Grad {
 	return grad
 }
 
+// windowMask marks the window timesteps of a RolloutSet
+// from an anyrl.Chunker, excluding burn-in timesteps.
+//
+// A nil *windowMask marks every timestep.
+type windowMask struct {
+	Mask        lazyseq.Tape
+	NumUnmasked int
+}
+
+func newWindowMask(chunks *anyrl.Chunks) *windowMask {
+	return &windowMask{Mask: chunks.Mask(), NumUnmasked: chunks.NumWindowSteps()}
+}
+
+// Seqs appends the mask, if there is one, to a list of
+// sequences.
+func (w *windowMask) Seqs(seqs ...lazyseq.Rereader) []lazyseq.Rereader {
+	if w == nil {
+		return seqs
+	}
+	return append(seqs, lazyseq.TapeRereader(w.Mask))
+}
+
+// Mean averages a sequence over the window timesteps of
+// r, assuming that the sequence is zero at every masked
+// timestep.
+func (w *windowMask) Mean(r *anyrl.RolloutSet, seq lazyseq.Rereader) anydiff.Res {
+	mean := lazyseq.Mean(seq)
+	if w == nil {
+		return mean
+	}
+	// Mean divides by every timestep, including the masked
+	// ones.
+	scale := float64(r.NumSteps()) / float64(w.NumUnmasked)
+	return anydiff.Scale(mean, mean.Output().Creator().MakeNumeric(scale))
+}
+
 func (p *PG) actionJudger() ActionJudger {
 	if p.ActionJudger == nil {
 		return &TotalJudger{Normalize: true}
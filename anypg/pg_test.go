@@ -0,0 +1,58 @@
+package anypg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anynet"
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/anyvec/anyvec64"
+	"github.com/unixpickle/lazyseq"
+)
+
+func TestPGRunChunks(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	r := rolloutsForTest(c)
+
+	block := &anyrnn.LayerBlock{
+		Layer: anynet.Net{
+			anynet.NewFC(c, 3, 2),
+			anynet.Tanh,
+			anynet.NewFC(c, 2, 2),
+		},
+	}
+	pg := &PG{
+		Policy: func(in lazyseq.Rereader) lazyseq.Rereader {
+			return lazyseq.Lazify(anyrnn.Map(lazyseq.Unlazify(in), block))
+		},
+		Params:      anynet.AllParameters(block),
+		ActionSpace: anyrl.Softmax{},
+		Regularizer: &EntropyReg{
+			Entropyer: anyrl.Softmax{},
+			Coeff:     0.5,
+		},
+	}
+
+	// With a feed-forward policy, the windows should give
+	// the same gradient as the full rollouts, since burn-in
+	// timesteps are excluded.
+	expected := pg.Run(r)
+	chunks := (&anyrl.Chunker{WindowSize: 2, BurnIn: 1}).Chunk(r)
+	actual := pg.RunChunks(chunks)
+	testGradsEquiv(t, expected, actual)
+}
+
+func testGradsEquiv(t *testing.T, expected, actual anydiff.Grad) {
+	for param, expectedVec := range expected {
+		expectedData := expectedVec.Data().([]float64)
+		actualData := actual[param].Data().([]float64)
+		for i, x := range expectedData {
+			a := actualData[i]
+			if math.Abs(x-a) > 1e-6*math.Max(1, math.Abs(x)) {
+				t.Errorf("param %p: entry %d should be %f but got %f", param, i, x, a)
+			}
+		}
+	}
+}
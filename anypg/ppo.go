@@ -110,11 +110,9 @@ type PPO struct {
 // as the value function is trained.
 func (p *PPO) Advantage(r *anyrl.RolloutSet) lazyseq.Tape {
 	judger := &GAEJudger{
-		ValueFunc: func(inputs lazyseq.Rereader) <-chan *anyseq.Batch {
-			return p.Critic(p.applyBaseIn(inputs)).Forward()
-		},
-		Discount: p.Discount,
-		Lambda:   p.Lambda,
+		ValueFunc: p.valueFunc,
+		Discount:  p.Discount,
+		Lambda:    p.Lambda,
 	}
 	return judger.JudgeActions(r).Tape(r.Inputs.Creator())
 }
@@ -126,17 +124,70 @@ func (p *PPO) Advantage(r *anyrl.RolloutSet) lazyseq.Tape {
 // This may be called multiple times per batch to fully
 // maximize the objective.
 //
+// The critic is trained towards the discounted returns.
+// For truncated episodes, the returns are bootstrapped
+// with the critic's current value for the final
+// observation.
+//
 // If p.Params is empty, then an empty gradient and nil
 // PPOTerms are returned.
 func (p *PPO) Run(r *anyrl.RolloutSet, adv lazyseq.Tape) (anydiff.Grad, *PPOTerms) {
+	return p.run(r, adv, p.criticTargets(r), nil)
+}
+
+// RunChunks is like Run, but for windows produced by an
+// anyrl.Chunker.
+//
+// The advantages should be computed for the full
+// rollouts and then split, for example with
+// chunks.SplitTape(p.Advantage(chunks.Full)).
+//
+// The critic targets are computed from the full
+// rollouts, and burn-in timesteps are excluded from every
+// term of the objective.
+// The terms are averaged over the window timesteps only,
+// so they are on the same scale as the terms from Run.
+//
+// Recurrent policies only see the RNN state that the
+// Chunker's burn-in recomputes.
+// For the exact state, use a negative Chunker.BurnIn.
+func (p *PPO) RunChunks(chunks *anyrl.Chunks, adv lazyseq.Tape) (anydiff.Grad,
+	*PPOTerms) {
+	targetValues := p.criticTargets(chunks.Full)
+	return p.run(chunks.Rollouts, adv, chunks.SplitRewards(targetValues),
+		newWindowMask(chunks))
+}
+
+// criticTargets computes the discounted returns which the
+// critic is trained to predict.
+// Truncated episodes are bootstrapped with the critic's
+// value for their final observation.
+func (p *PPO) criticTargets(r *anyrl.RolloutSet) anyrl.Rewards {
+	judger := &QJudger{Discount: p.Discount, ValueFunc: p.valueFunc}
+	return judger.JudgeActions(r)
+}
+
+func (p *PPO) valueFunc(inputs lazyseq.Rereader) <-chan *anyseq.Batch {
+	return p.Critic(p.applyBaseIn(inputs)).Forward()
+}
+
+func (p *PPO) run(r *anyrl.RolloutSet, adv lazyseq.Tape, targetValues anyrl.Rewards,
+	mask *windowMask) (anydiff.Grad, *PPOTerms) {
 	grad := anydiff.NewGrad(p.Params...)
 	if len(grad) == 0 {
 		return grad, nil
 	}
 	c := r.Creator()
-	targetValues := (&QJudger{Discount: p.Discount}).JudgeActions(r)
 
 	objective := p.runActorCritic(r, func(actor, critic lazyseq.Rereader) anydiff.Res {
+		seqs := mask.Seqs(
+			actor,
+			critic,
+			lazyseq.TapeRereader(r.AgentOuts),
+			lazyseq.TapeRereader(r.Actions),
+			lazyseq.TapeRereader(adv),
+			lazyseq.TapeRereader(targetValues.Tape(c)),
+		)
 		obj := lazyseq.MapN(
 			func(n int, v ...anydiff.Res) anydiff.Res {
 				actor, critic := v[0], v[1]
@@ -167,17 +218,19 @@ func (p *PPO) Run(r *anyrl.RolloutSet, adv lazyseq.Tape) (anydiff.Grad, *PPOTerm
 					regTerm = anydiff.NewConst(c.MakeVector(n))
 				}
 
+				if len(v) > 6 {
+					mask := v[6]
+					advTerm = anydiff.Mul(advTerm, mask)
+					criticTerm = anydiff.Mul(criticTerm, mask)
+					regTerm = anydiff.Mul(regTerm, mask)
+				}
+
 				cm := anynet.ConcatMixer{}
 				return cm.Mix(cm.Mix(advTerm, criticTerm, n), regTerm, n)
 			},
-			actor,
-			critic,
-			lazyseq.TapeRereader(r.AgentOuts),
-			lazyseq.TapeRereader(r.Actions),
-			lazyseq.TapeRereader(adv),
-			lazyseq.TapeRereader(targetValues.Tape(c)),
+			seqs...,
 		)
-		return lazyseq.Mean(obj)
+		return mask.Mean(r, obj)
 	})
 	objective.Propagate(anyvec.Ones(c, 3), grad)
 
@@ -0,0 +1,91 @@
+package anypg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anynet"
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/anyvec/anyvec64"
+	"github.com/unixpickle/lazyseq"
+)
+
+func TestPPOCriticTargetsTruncated(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	rollouts := rolloutsForTest(c)
+	rollouts.Truncated = []bool{true, false, false}
+	rollouts.FinalObs = [][]float64{{1, 2, 3}, nil, nil}
+
+	ppo := &PPO{
+		Critic: func(in lazyseq.Rereader) lazyseq.Rereader {
+			return lazyseq.Map(in, func(v anydiff.Res, n int) anydiff.Res {
+				values := c.MakeVector(n)
+				values.AddScalar(c.MakeNumeric(2))
+				return anydiff.NewConst(values)
+			})
+		},
+		Discount: 0.9,
+	}
+
+	actual := ppo.criticTargets(rollouts)
+	expected := (&QJudger{Discount: 0.9}).JudgeActions(rollouts)
+	for i, seq := range expected {
+		if !rollouts.Truncated[i] {
+			continue
+		}
+		for t := range seq {
+			seq[t] += 2 * math.Pow(0.9, float64(len(seq)-t))
+		}
+	}
+
+	testRewardsEquiv(t, actual, expected)
+}
+
+func TestPPORunChunks(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	rollouts := rolloutsForTest(c)
+
+	actor := anynet.NewFC(c, 3, 2)
+	critic := anynet.NewFC(c, 3, 1)
+	layerFunc := func(layer anynet.Layer) func(lazyseq.Rereader) lazyseq.Rereader {
+		return func(in lazyseq.Rereader) lazyseq.Rereader {
+			return lazyseq.Map(in, func(v anydiff.Res, n int) anydiff.Res {
+				return layer.Apply(v, n)
+			})
+		}
+	}
+	ppo := &PPO{
+		Params:      append(actor.Parameters(), critic.Parameters()...),
+		Actor:       layerFunc(actor),
+		Critic:      layerFunc(critic),
+		ActionSpace: anyrl.Softmax{},
+		Discount:    0.9,
+	}
+
+	outs, writer := lazyseq.ReferenceTape(c)
+	for item := range ppo.Actor(lazyseq.TapeRereader(rollouts.Inputs)).Forward() {
+		writer <- item.Reduce(item.Present)
+	}
+	close(writer)
+	rollouts.AgentOuts = outs
+
+	// With a feed-forward agent, the windows should give
+	// the same terms as the full rollouts, since burn-in
+	// timesteps are excluded from the average.
+	adv := rollouts.Rewards.Tape(c)
+	_, expected := ppo.Run(rollouts, adv)
+	chunks := (&anyrl.Chunker{WindowSize: 2, BurnIn: 1}).Chunk(rollouts)
+	_, actual := ppo.RunChunks(chunks, chunks.SplitTape(adv))
+
+	pairs := [][2]float64{
+		{expected.MeanAdvantage.(float64), actual.MeanAdvantage.(float64)},
+		{expected.MeanCritic.(float64), actual.MeanCritic.(float64)},
+		{expected.MeanRegularization.(float64), actual.MeanRegularization.(float64)},
+	}
+	for i, pair := range pairs {
+		if math.Abs(pair[0]-pair[1]) > 1e-8 {
+			t.Errorf("term %d: expected %f but got %f", i, pair[0], pair[1])
+		}
+	}
+}
@@ -57,7 +57,20 @@ type TRPO struct {
 // Run computes a step to improve the agent's performance
 // on the rollouts.
 func (t *TRPO) Run(r *anyrl.RolloutSet) anydiff.Grad {
-	res := t.NaturalPG.run(r)
+	return t.run(r, nil)
+}
+
+// RunChunks is like Run, but for windows produced by an
+// anyrl.Chunker.
+//
+// See NaturalPG.RunChunks for details.
+// The line search also ignores burn-in timesteps.
+func (t *TRPO) RunChunks(chunks *anyrl.Chunks) anydiff.Grad {
+	return t.run(chunks.Rollouts, chunks)
+}
+
+func (t *TRPO) run(r *anyrl.RolloutSet, chunks *anyrl.Chunks) anydiff.Grad {
+	res := t.NaturalPG.run(r, chunks)
 	if res.ZeroGrad {
 		return res.Grad
 	}
@@ -80,7 +93,8 @@ func (t *TRPO) stepSize(r *naturalPGRes) anyvec.Numeric {
 	c := r.Creator()
 	ops := c.NumOps()
 	r.ReducedOut.Reuse()
-	dotProd := dotGrad(r.Grad, t.applyFisher(r.ReducedRollouts, r.Grad, r.ReducedOut))
+	dotProd := dotGrad(r.Grad, t.applyFisher(r.ReducedRollouts, r.Grad, r.ReducedOut,
+		r.Mask))
 	zero := c.MakeNumeric(0)
 
 	// The fisher-vector product might be less than zero due
@@ -98,7 +112,11 @@ func (t *TRPO) stepSize(r *naturalPGRes) anyvec.Numeric {
 func (t *TRPO) acceptable(r *anyrl.RolloutSet, npg *naturalPGRes) bool {
 	c := npg.Creator()
 	inSeq := lazyseq.TapeRereader(r.Inputs)
-	rewardSeq := lazyseq.TapeRereader(t.actionJudger().JudgeActions(r).Tape(c))
+	judger := t.actionJudger()
+	if npg.Chunks != nil {
+		judger = &ChunkJudger{Judger: judger, Chunks: npg.Chunks}
+	}
+	rewardSeq := lazyseq.TapeRereader(judger.JudgeActions(r).Tape(c))
 	newOutSeq := t.apply(inSeq, t.steppedPolicy(npg.Grad))
 	sampledOut := lazyseq.TapeRereader(r.Actions)
 	npg.PolicyOut.Reuse()
@@ -118,6 +136,9 @@ func (t *TRPO) acceptable(r *anyrl.RolloutSet, npg *naturalPGRes) bool {
 
 		rewardChange := anydiff.Sub(anydiff.Mul(probRatio, reward), reward)
 		kl := t.ActionSpace.KL(oldOut, newOut, n)
+		if len(v) > 4 {
+			kl = anydiff.Mul(kl, v[4])
+		}
 
 		// Put the rewards and kl divergences side-by-side.
 		joined := c.Concat(rewardChange.Output(), kl.Output())
@@ -125,9 +146,9 @@ func (t *TRPO) acceptable(r *anyrl.RolloutSet, npg *naturalPGRes) bool {
 		anyvec.Transpose(joined, transposed, 2)
 
 		return anydiff.NewConst(transposed)
-	}, rewardSeq, npg.PolicyOut, newOutSeq, sampledOut)
+	}, npg.Mask.Seqs(rewardSeq, npg.PolicyOut, newOutSeq, sampledOut)...)
 
-	outStats := lazyseq.Mean(mappedOut).Output()
+	outStats := npg.Mask.Mean(r, mappedOut).Output()
 	improvement := anyvec.Sum(outStats.Slice(0, 1))
 	kl := anyvec.Sum(outStats.Slice(1, 2))
 
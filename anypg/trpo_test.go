@@ -45,3 +45,31 @@ func TestTRPOImprovement(t *testing.T) {
 		t.Errorf("TRPO gave a direction of decrease")
 	}
 }
+
+func TestTRPORunChunks(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	r := rolloutsForTest(c)
+
+	block := &anyrnn.LayerBlock{
+		Layer: anynet.Net{
+			anynet.NewFC(c, 3, 2),
+			anynet.Tanh,
+			anynet.NewFC(c, 2, 2),
+		},
+	}
+	trpo := &TRPO{
+		NaturalPG: NaturalPG{
+			Policy:      block,
+			Params:      block.Parameters(),
+			ActionSpace: anyrl.Softmax{},
+			Iters:       14,
+		},
+	}
+
+	// With a feed-forward policy, the line search should
+	// not depend on the burn-in timesteps.
+	expected := trpo.Run(r)
+	chunks := (&anyrl.Chunker{WindowSize: 2, BurnIn: 1}).Chunk(r)
+	actual := trpo.RunChunks(chunks)
+	testGradsEquiv(t, expected, actual)
+}
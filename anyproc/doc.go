@@ -0,0 +1,12 @@
+// Package anyproc runs anyrl.Env instances in child
+// processes.
+//
+// This isolates a training process from environments
+// which may crash, such as ones that wrap unstable C
+// libraries.
+//
+// The child process calls Serve to expose an environment
+// over its standard input and output.
+// The parent process uses an Env to talk to the child,
+// restarting it whenever it dies.
+package anyproc
@@ -0,0 +1,282 @@
+package anyproc
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/unixpickle/essentials"
+)
+
+// DefaultMaxRestarts is the default value for
+// Env.MaxRestarts.
+const DefaultMaxRestarts = 3
+
+// Env is an anyrl.Env which runs an environment in a
+// child process.
+//
+// The child process is started lazily by Reset, and it is
+// restarted whenever it dies.
+//
+// If the child dies during an episode, Step restarts it
+// and ends the episode early.
+// Such episodes are reported as truncated, since they did
+// not reach a true terminal state.
+// This way, rollouts can continue despite crashes.
+//
+// An Env should be closed to kill the child process.
+type Env struct {
+	// MakeCmd creates a command which runs the child.
+	// The child should call Serve.
+	//
+	// The command's standard input and output must not be
+	// set, since they are used to communicate with the
+	// child.
+	// If the command's standard error is nil, it is set
+	// to os.Stderr.
+	MakeCmd func() *exec.Cmd
+
+	// MaxRestarts is the maximum number of times Reset may
+	// restart a crashed child before giving up.
+	// If it is 0, DefaultMaxRestarts is used.
+	MaxRestarts int
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	enc     *gob.Encoder
+	dec     *gob.Decoder
+	lastObs []float64
+
+	truncated bool
+	crashed   bool
+	numCrash  int
+
+	started    bool
+	seed       *int64
+	seedOffset int64
+	seeded     bool
+}
+
+// Reset resets the environment, starting a new child if
+// necessary.
+func (e *Env) Reset() ([]float64, error) {
+	return e.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it stops early if the
+// context is done.
+//
+// If the context is done before the child responds, the
+// child is killed, and the context's error is returned.
+// The next call to Reset starts a new child.
+// This is not counted as a crash.
+func (e *Env) ResetCtx(ctx context.Context) ([]float64, error) {
+	obs, err := e.reset(ctx)
+	if err != nil && err != ctx.Err() {
+		err = essentials.AddCtx("reset process env", err)
+	}
+	return obs, err
+}
+
+// Step takes a step in the environment.
+func (e *Env) Step(action []float64) ([]float64, float64, bool, error) {
+	return e.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it stops early if the context
+// is done.
+//
+// See ResetCtx for details.
+func (e *Env) StepCtx(ctx context.Context, action []float64) ([]float64, float64,
+	bool, error) {
+	obs, rew, done, err := e.step(ctx, action)
+	if err != nil && err != ctx.Err() {
+		err = essentials.AddCtx("step process env", err)
+	}
+	return obs, rew, done, err
+}
+
+func (e *Env) reset(ctx context.Context) ([]float64, error) {
+	e.truncated = false
+	e.crashed = false
+
+	maxRestarts := e.MaxRestarts
+	if maxRestarts == 0 {
+		maxRestarts = DefaultMaxRestarts
+	}
+	for i := 0; ; i++ {
+		resp, err := e.callReset(ctx)
+		if err == nil {
+			if resp.Err != nil {
+				return nil, errors.New(*resp.Err)
+			}
+			e.lastObs = resp.Obs
+			return resp.Obs, nil
+		}
+		if i == maxRestarts || err == ctx.Err() {
+			return nil, err
+		}
+	}
+}
+
+func (e *Env) step(ctx context.Context, action []float64) ([]float64, float64, bool,
+	error) {
+	if e.cmd == nil {
+		return nil, 0, false, errors.New("environment must be reset")
+	}
+	resp, err := e.call(ctx, &request{Type: requestStep, Action: action})
+	if err == ctx.Err() && err != nil {
+		return nil, 0, false, err
+	} else if err != nil {
+		e.crashed = true
+		e.truncated = true
+		return e.lastObs, 0, true, nil
+	}
+	if resp.Err != nil {
+		return nil, 0, false, errors.New(*resp.Err)
+	}
+	e.lastObs = resp.Obs
+	e.truncated = resp.Truncated
+	return resp.Obs, resp.Reward, resp.Done, nil
+}
+
+func (e *Env) callReset(ctx context.Context) (*response, error) {
+	if e.seed != nil && (e.cmd == nil || !e.seeded) {
+		if e.cmd == nil {
+			if err := e.startChild(); err != nil {
+				return nil, err
+			}
+		}
+		seed := *e.seed + e.seedOffset
+		if _, err := e.call(ctx, &request{Type: requestSeed, Seed: seed}); err != nil {
+			return nil, err
+		}
+		e.seeded = true
+	}
+	return e.call(ctx, &request{Type: requestReset})
+}
+
+// Seed seeds the child's environment if it is a Seeder.
+//
+// The seed is sent to the child by the next call to
+// Reset.
+//
+// Whenever the child is restarted (e.g. after a crash),
+// the new child is seeded before its first reset.
+// The n-th restarted child gets the seed plus n, so that
+// it does not repeat the episodes of the old child.
+func (e *Env) Seed(seed int64) {
+	e.seed = &seed
+	e.seedOffset = 0
+	e.seeded = false
+}
+
+// Truncated returns true if the last episode was ended
+// by a time limit or by a crash.
+func (e *Env) Truncated() bool {
+	return e.truncated
+}
+
+// Crashed returns true if the last episode was ended
+// because the child process died.
+func (e *Env) Crashed() bool {
+	return e.crashed
+}
+
+// NumCrashes returns the number of times the child
+// process has died.
+func (e *Env) NumCrashes() int {
+	return e.numCrash
+}
+
+// Close kills the child process, if there is one.
+func (e *Env) Close() error {
+	if e.cmd == nil {
+		return nil
+	}
+	e.stopChild()
+	return nil
+}
+
+// call sends a request to the child, starting the child
+// if necessary.
+//
+// If the child dies, it is cleaned up and an error is
+// returned.
+// If ctx is done first, the child is killed and the
+// context's error is returned.
+func (e *Env) call(ctx context.Context, req *request) (*response, error) {
+	if e.cmd == nil {
+		if err := e.startChild(); err != nil {
+			return nil, err
+		}
+	}
+	type result struct {
+		resp *response
+		err  error
+	}
+	resChan := make(chan result, 1)
+	enc, dec := e.enc, e.dec
+	go func() {
+		var r result
+		r.resp, r.err = call(enc, dec, req)
+		resChan <- r
+	}()
+	select {
+	case r := <-resChan:
+		if r.err != nil {
+			e.numCrash++
+			e.stopChild()
+			return nil, r.err
+		}
+		return r.resp, nil
+	case <-ctx.Done():
+		// Killing the child unblocks the pending call.
+		e.cmd.Process.Kill()
+		<-resChan
+		e.stopChild()
+		return nil, ctx.Err()
+	}
+}
+
+func (e *Env) startChild() (err error) {
+	defer essentials.AddCtxTo("start child", &err)
+	cmd := e.MakeCmd()
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	e.cmd = cmd
+	e.stdin = stdin
+	e.enc = gob.NewEncoder(stdin)
+	e.dec = gob.NewDecoder(stdout)
+	if e.started {
+		e.seedOffset++
+	}
+	e.started = true
+	e.seeded = false
+	return nil
+}
+
+func (e *Env) stopChild() {
+	e.stdin.Close()
+	e.cmd.Process.Kill()
+	e.cmd.Wait()
+	e.cmd = nil
+	e.stdin = nil
+	e.enc = nil
+	e.dec = nil
+}
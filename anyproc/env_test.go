@@ -0,0 +1,188 @@
+package anyproc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/anyrl"
+)
+
+const helperEnvVar = "ANYPROC_TEST_HELPER"
+
+// crashEnv is an environment which exits the process
+// when the action is 2, fails when the action is 3, and
+// hangs when the action is 4.
+//
+// The initial observation is the last seed.
+type crashEnv struct {
+	timestep float64
+	seed     int64
+}
+
+func (c *crashEnv) Reset() ([]float64, error) {
+	c.timestep = 0
+	return []float64{float64(c.seed)}, nil
+}
+
+func (c *crashEnv) Seed(seed int64) {
+	c.seed = seed
+}
+
+func (c *crashEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	switch action[0] {
+	case 2:
+		os.Exit(1)
+	case 3:
+		return nil, 0, false, errors.New("bad action")
+	case 4:
+		select {}
+	}
+	c.timestep++
+	return []float64{c.timestep}, action[0], c.timestep == 3, nil
+}
+
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv(helperEnvVar) != "1" {
+		return
+	}
+	if err := Serve(&anyrl.MaxStepsEnv{Env: &crashEnv{}, MaxSteps: 5}); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestEnv(t *testing.T) {
+	env := newTestEnv()
+	defer env.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := env.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		var total float64
+		for {
+			obs, rew, done, err := env.Step([]float64{1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += rew
+			if done {
+				if obs[0] != 3 {
+					t.Errorf("unexpected final observation: %v", obs)
+				}
+				break
+			}
+		}
+		if total != 3 || env.Truncated() {
+			t.Errorf("unexpected total %f or truncation", total)
+		}
+	}
+
+	if _, _, _, err := env.Step([]float64{3}); err == nil {
+		t.Error("expected environment error")
+	}
+
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	_, _, done, err := env.Step([]float64{2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done || !env.Truncated() || !env.Crashed() || env.NumCrashes() != 1 {
+		t.Error("crash was not handled")
+	}
+
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := env.Step([]float64{1}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnvCtx(t *testing.T) {
+	env := newTestEnv()
+	defer env.Close()
+
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, _, _, err := env.StepCtx(ctx, []float64{4}); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline error but got %v", err)
+	}
+	if env.NumCrashes() != 0 {
+		t.Error("cancellation should not count as a crash")
+	}
+	if _, _, _, err := env.Step([]float64{1}); err == nil {
+		t.Error("expected error before reset")
+	}
+
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	obs, _, _, err := env.Step([]float64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obs[0] != 1 {
+		t.Errorf("unexpected observation: %v", obs)
+	}
+}
+
+func TestEnvSeed(t *testing.T) {
+	env := newTestEnv()
+	defer env.Close()
+
+	env.Seed(1337)
+	for i := 0; i < 2; i++ {
+		obs, err := env.Reset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if obs[0] != 1337 {
+			t.Errorf("reset %d: unexpected observation: %v", i, obs)
+		}
+	}
+}
+
+func TestEnvSeedRestart(t *testing.T) {
+	env := newTestEnv()
+	defer env.Close()
+
+	env.Seed(1337)
+	for i := 0; i < 2; i++ {
+		if _, err := env.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := env.Step([]float64{2}); err != nil {
+			t.Fatal(err)
+		}
+		if !env.Crashed() {
+			t.Fatal("expected crash")
+		}
+		obs, err := env.Reset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := float64(1338 + i); obs[0] != expected {
+			t.Errorf("restart %d: expected seed %f but got %v", i, expected, obs)
+		}
+	}
+}
+
+func newTestEnv() *Env {
+	return &Env{
+		MakeCmd: func() *exec.Cmd {
+			cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+			cmd.Env = append(os.Environ(), helperEnvVar+"=1")
+			return cmd
+		},
+	}
+}
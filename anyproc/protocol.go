@@ -0,0 +1,123 @@
+package anyproc
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/essentials"
+)
+
+type requestType int
+
+const (
+	requestReset requestType = iota
+	requestStep
+	requestSeed
+)
+
+// request is sent from the parent to the child.
+type request struct {
+	Type   requestType
+	Action []float64
+	Seed   int64
+}
+
+// response is sent from the child to the parent.
+type response struct {
+	Obs       []float64
+	Reward    float64
+	Done      bool
+	Truncated bool
+
+	Err *string
+}
+
+func newResponseErr(err error) *response {
+	if err == nil {
+		return &response{}
+	}
+	s := err.Error()
+	return &response{Err: &s}
+}
+
+// Serve runs an environment for a parent process, using
+// the standard input and output of the current process.
+//
+// Since standard output is used for the protocol, Serve
+// moves the protocol to a duplicate of the standard
+// output file descriptor and points file descriptor 1 at
+// standard error.
+// This way, output from C libraries in the child cannot
+// corrupt the protocol.
+// On platforms without Unix file descriptors, only
+// os.Stdout is redirected.
+//
+// This blocks until the parent closes the connection.
+func Serve(env anyrl.Env) error {
+	out, err := redirectStdout()
+	if err != nil {
+		return essentials.AddCtx("serve env", err)
+	}
+	defer out.Close()
+	return ServeConn(env, os.Stdin, out)
+}
+
+// ServeConn runs an environment for a parent process,
+// reading requests from r and writing responses to w.
+//
+// This blocks until r is closed.
+// Errors from the environment are sent to the parent
+// rather than being returned.
+func ServeConn(env anyrl.Env, r io.Reader, w io.Writer) (err error) {
+	defer essentials.AddCtxTo("serve env", &err)
+	dec := gob.NewDecoder(r)
+	enc := gob.NewEncoder(w)
+	for {
+		var req request
+		if err := dec.Decode(&req); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var resp *response
+		switch req.Type {
+		case requestReset:
+			obs, err := env.Reset()
+			resp = newResponseErr(err)
+			resp.Obs = obs
+		case requestStep:
+			obs, rew, done, err := env.Step(req.Action)
+			resp = newResponseErr(err)
+			resp.Obs = obs
+			resp.Reward = rew
+			resp.Done = done
+			resp.Truncated = done && anyrl.EnvTruncated(env)
+		case requestSeed:
+			anyrl.SeedEnv(env, req.Seed)
+			resp = &response{}
+		default:
+			return fmt.Errorf("unknown request type: %v", req.Type)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// call sends a request and waits for the response.
+//
+// Errors from the environment itself are stored in the
+// response's Err field.
+func call(enc *gob.Encoder, dec *gob.Decoder, req *request) (*response, error) {
+	if err := enc.Encode(req); err != nil {
+		return nil, err
+	}
+	var resp response
+	if err := dec.Decode(&resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,21 @@
+package anyproc
+
+import (
+	"os"
+	"syscall"
+)
+
+func redirectStdout() (*os.File, error) {
+	fd, err := syscall.Dup(1)
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(fd)
+	// Dup2 is not available on every Linux architecture.
+	if err := syscall.Dup3(2, 1, 0); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	os.Stdout = os.Stderr
+	return os.NewFile(uintptr(fd), "protocol"), nil
+}
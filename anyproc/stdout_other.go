@@ -0,0 +1,15 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package anyproc
+
+import "os"
+
+// redirectStdout can only redirect Go-level writes on
+// this platform, since file descriptors cannot be
+// duplicated portably.
+func redirectStdout() (*os.File, error) {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	return out, nil
+}
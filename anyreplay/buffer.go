@@ -0,0 +1,154 @@
+package anyreplay
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// A Sample is a transition drawn from a Buffer.
+type Sample struct {
+	Transition *Transition
+
+	// ID identifies the transition within the buffer.
+	// It can be passed to PrioritizedBuffer.Update.
+	ID int64
+
+	// Weight is the importance sampling weight for the
+	// transition, which should scale its loss.
+	Weight float64
+}
+
+// A Buffer stores a bounded number of transitions.
+//
+// When a Buffer is full, adding a transition overwrites
+// the oldest transition.
+//
+// All Buffer implementations in this package are safe to
+// use from multiple Goroutines.
+type Buffer interface {
+	// Len returns the number of stored transitions.
+	Len() int
+
+	// Cap returns the maximum number of transitions.
+	Cap() int
+
+	// Add adds a transition to the buffer.
+	Add(t *Transition)
+
+	// Sample samples n transitions with replacement.
+	//
+	// It panics if the buffer is empty.
+	Sample(n int) []*Sample
+}
+
+// UniformBuffer is a Buffer which samples every
+// transition with equal probability.
+type UniformBuffer struct {
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	lock sync.Mutex
+	ring ring
+}
+
+// NewUniformBuffer creates an empty UniformBuffer.
+func NewUniformBuffer(capacity int) *UniformBuffer {
+	return &UniformBuffer{ring: newRing(capacity)}
+}
+
+// Len returns the number of stored transitions.
+func (u *UniformBuffer) Len() int {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.ring.Len()
+}
+
+// Cap returns the maximum number of transitions.
+func (u *UniformBuffer) Cap() int {
+	return len(u.ring.transitions)
+}
+
+// Add adds a transition to the buffer.
+func (u *UniformBuffer) Add(t *Transition) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.ring.Add(t)
+}
+
+// Sample samples n transitions uniformly at random.
+//
+// Every sample has a weight of 1.
+func (u *UniformBuffer) Sample(n int) []*Sample {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	size := u.ring.Len()
+	if size == 0 {
+		panic("cannot sample from empty buffer")
+	}
+	res := make([]*Sample, n)
+	for i := range res {
+		var slot int
+		if u.Rand == nil {
+			slot = rand.Intn(size)
+		} else {
+			slot = u.Rand.Intn(size)
+		}
+		res[i] = u.ring.Sample(slot, 1)
+	}
+	return res
+}
+
+// ring stores transitions in a circular buffer.
+//
+// Each transition is given an ID, which is the number of
+// transitions that were added before it.
+type ring struct {
+	transitions []*Transition
+	numAdded    int64
+}
+
+func newRing(capacity int) ring {
+	if capacity <= 0 {
+		panic("capacity must be positive")
+	}
+	return ring{transitions: make([]*Transition, capacity)}
+}
+
+func (r *ring) Len() int {
+	if r.numAdded < int64(len(r.transitions)) {
+		return int(r.numAdded)
+	}
+	return len(r.transitions)
+}
+
+// Add stores a transition and returns its slot.
+func (r *ring) Add(t *Transition) int {
+	slot := int(r.numAdded % int64(len(r.transitions)))
+	r.transitions[slot] = t
+	r.numAdded++
+	return slot
+}
+
+// Slot finds the slot for a transition ID.
+//
+// The second return value is false if the transition was
+// overwritten or never added.
+func (r *ring) Slot(id int64) (int, bool) {
+	if id < 0 || id >= r.numAdded || id < r.numAdded-int64(len(r.transitions)) {
+		return 0, false
+	}
+	return int(id % int64(len(r.transitions))), true
+}
+
+// Sample creates a Sample for the transition in a slot.
+func (r *ring) Sample(slot int, weight float64) *Sample {
+	capacity := int64(len(r.transitions))
+	newest := (r.numAdded - 1) % capacity
+	id := r.numAdded - 1 - (newest-int64(slot)+capacity)%capacity
+	return &Sample{
+		Transition: r.transitions[slot],
+		ID:         id,
+		Weight:     weight,
+	}
+}
@@ -0,0 +1,51 @@
+package anyreplay
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestUniformBuffer(t *testing.T) {
+	buf := NewUniformBuffer(3)
+	buf.Rand = rand.New(rand.NewSource(1337))
+	for i := 0; i < 5; i++ {
+		buf.Add(&Transition{Reward: float64(i)})
+	}
+	if buf.Len() != 3 || buf.Cap() != 3 {
+		t.Fatalf("unexpected size: len=%d cap=%d", buf.Len(), buf.Cap())
+	}
+
+	counts := map[float64]int{}
+	for _, s := range buf.Sample(3000) {
+		if s.ID != int64(s.Transition.Reward) {
+			t.Fatalf("bad ID %d for reward %f", s.ID, s.Transition.Reward)
+		}
+		if s.Weight != 1 {
+			t.Fatalf("unexpected weight: %f", s.Weight)
+		}
+		counts[s.Transition.Reward]++
+	}
+	for _, reward := range []float64{2, 3, 4} {
+		if counts[reward] < 900 || counts[reward] > 1100 {
+			t.Errorf("reward %f sampled %d times", reward, counts[reward])
+		}
+	}
+	if len(counts) != 3 {
+		t.Errorf("unexpected samples: %v", counts)
+	}
+}
+
+func TestRingSlot(t *testing.T) {
+	r := newRing(3)
+	for i := 0; i < 5; i++ {
+		r.Add(&Transition{})
+	}
+	for id := int64(-1); id < 6; id++ {
+		slot, ok := r.Slot(id)
+		if ok != (id >= 2 && id < 5) {
+			t.Errorf("ID %d: unexpected ok value %v", id, ok)
+		} else if ok && slot != int(id%3) {
+			t.Errorf("ID %d: unexpected slot %d", id, slot)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+// Package anyreplay implements experience replay for
+// off-policy reinforcement learning algorithms.
+//
+// Transitions can be gathered from anyrl.RolloutSets or
+// added one environment step at a time, and they can be
+// assembled into n-step transitions with NStep.
+// Buffers store transitions in a fixed-capacity ring and
+// sample them either uniformly or in proportion to a
+// priority, as described in
+// https://arxiv.org/abs/1511.05952.
+package anyreplay
@@ -0,0 +1,188 @@
+package anyreplay
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// PrioritizedBuffer is a Buffer which samples transitions
+// in proportion to their priorities, as in proportional
+// prioritized experience replay.
+//
+// A transition with priority p is sampled in proportion
+// to p^alpha.
+// New transitions are given the maximum priority seen so
+// far, so they are likely to be sampled at least once.
+type PrioritizedBuffer struct {
+	// Beta controls the importance sampling weights.
+	// At 1, the weights fully correct for the bias from
+	// prioritized sampling.
+	// At 0, every weight is 1.
+	//
+	// It is typically annealed towards 1 over the course
+	// of training.
+	Beta float64
+
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	lock        sync.Mutex
+	alpha       float64
+	ring        ring
+	tree        *sumTree
+	maxPriority float64
+}
+
+// NewPrioritizedBuffer creates an empty
+// PrioritizedBuffer.
+//
+// The alpha argument controls how much prioritization is
+// used, where 0 corresponds to uniform sampling.
+func NewPrioritizedBuffer(capacity int, alpha, beta float64) *PrioritizedBuffer {
+	return &PrioritizedBuffer{
+		Beta:        beta,
+		alpha:       alpha,
+		ring:        newRing(capacity),
+		tree:        newSumTree(capacity),
+		maxPriority: 1,
+	}
+}
+
+// Len returns the number of stored transitions.
+func (p *PrioritizedBuffer) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.ring.Len()
+}
+
+// Cap returns the maximum number of transitions.
+func (p *PrioritizedBuffer) Cap() int {
+	return len(p.ring.transitions)
+}
+
+// Add adds a transition with the maximum priority.
+func (p *PrioritizedBuffer) Add(t *Transition) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	slot := p.ring.Add(t)
+	p.tree.Set(slot, math.Pow(p.maxPriority, p.alpha))
+}
+
+// Sample samples n transitions in proportion to their
+// priorities.
+//
+// The range of priorities is split into n equal segments,
+// and one transition is sampled from each segment.
+//
+// The importance sampling weights are normalized so that
+// the largest weight in the batch is 1.
+func (p *PrioritizedBuffer) Sample(n int) []*Sample {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	size := p.ring.Len()
+	if size == 0 {
+		panic("cannot sample from empty buffer")
+	}
+
+	total := p.tree.Total()
+	res := make([]*Sample, n)
+	var maxWeight float64
+	for i := range res {
+		var r float64
+		if p.Rand == nil {
+			r = rand.Float64()
+		} else {
+			r = p.Rand.Float64()
+		}
+		slot := p.tree.Find(total * (float64(i) + r) / float64(n))
+		prob := p.tree.Get(slot) / total
+		weight := math.Pow(float64(size)*prob, -p.Beta)
+		maxWeight = math.Max(maxWeight, weight)
+		res[i] = p.ring.Sample(slot, weight)
+	}
+	for _, s := range res {
+		s.Weight /= maxWeight
+	}
+	return res
+}
+
+// Update sets the priorities of transitions, typically to
+// the magnitudes of their TD errors.
+//
+// Priorities should be positive, since transitions with
+// a priority of 0 are never sampled.
+// A small constant is commonly added to TD errors to
+// ensure this.
+//
+// IDs for transitions which have been overwritten since
+// they were sampled are ignored.
+func (p *PrioritizedBuffer) Update(ids []int64, priorities []float64) {
+	if len(ids) != len(priorities) {
+		panic("length mismatch")
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for i, id := range ids {
+		slot, ok := p.ring.Slot(id)
+		if !ok {
+			continue
+		}
+		p.tree.Set(slot, math.Pow(priorities[i], p.alpha))
+		p.maxPriority = math.Max(p.maxPriority, priorities[i])
+	}
+}
+
+// sumTree is a binary tree where each node stores the sum
+// of the values in its subtree.
+//
+// Nodes are stored like a binary heap, with the root at
+// index 1 and the leaves at indices [size, 2*size).
+type sumTree struct {
+	size  int
+	nodes []float64
+}
+
+func newSumTree(size int) *sumTree {
+	return &sumTree{size: size, nodes: make([]float64, 2*size)}
+}
+
+// Total returns the sum of all the leaves.
+func (s *sumTree) Total() float64 {
+	return s.nodes[1]
+}
+
+// Get returns the value of a leaf.
+func (s *sumTree) Get(leaf int) float64 {
+	return s.nodes[leaf+s.size]
+}
+
+// Set updates the value of a leaf.
+func (s *sumTree) Set(leaf int, value float64) {
+	idx := leaf + s.size
+	s.nodes[idx] = value
+	for idx > 1 {
+		idx /= 2
+		s.nodes[idx] = s.nodes[2*idx] + s.nodes[2*idx+1]
+	}
+}
+
+// Find finds the leaf where the cumulative sum of the
+// leaves passes x.
+//
+// Leaves with a value of 0 are never returned, as long as
+// the total is non-zero.
+func (s *sumTree) Find(x float64) int {
+	idx := 1
+	for idx < s.size {
+		left := 2 * idx
+		if x < s.nodes[left] || s.nodes[left+1] == 0 {
+			idx = left
+		} else {
+			x -= s.nodes[left]
+			idx = left + 1
+		}
+	}
+	return idx - s.size
+}
@@ -0,0 +1,74 @@
+package anyreplay
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPrioritizedBuffer(t *testing.T) {
+	buf := NewPrioritizedBuffer(4, 0.5, 1)
+	buf.Rand = rand.New(rand.NewSource(1337))
+	for i := 0; i < 5; i++ {
+		buf.Add(&Transition{Reward: float64(i)})
+	}
+
+	// Transition 0 was overwritten, so its update is
+	// ignored.
+	buf.Update([]int64{0, 1, 2, 3, 4}, []float64{100, 1, 4, 9, 16})
+	probs := map[float64]float64{1: 0.1, 2: 0.2, 3: 0.3, 4: 0.4}
+
+	counts := map[float64]int{}
+	const numSamples = 10000
+	for _, s := range buf.Sample(numSamples) {
+		reward := s.Transition.Reward
+		if s.ID != int64(reward) {
+			t.Fatalf("bad ID %d for reward %f", s.ID, reward)
+		}
+		expectedWeight := 1 / (probs[reward] / probs[1])
+		if math.Abs(s.Weight-expectedWeight) > 1e-8 {
+			t.Errorf("reward %f: expected weight %f but got %f", reward,
+				expectedWeight, s.Weight)
+		}
+		counts[reward]++
+	}
+	for reward, prob := range probs {
+		frac := float64(counts[reward]) / numSamples
+		if math.Abs(frac-prob) > 0.02 {
+			t.Errorf("reward %f: expected frequency %f but got %f", reward, prob, frac)
+		}
+	}
+}
+
+func TestPrioritizedBufferNew(t *testing.T) {
+	buf := NewPrioritizedBuffer(3, 1, 0)
+	buf.Add(&Transition{Reward: 0})
+	buf.Update([]int64{0}, []float64{5})
+	buf.Add(&Transition{Reward: 1})
+	buf.Update([]int64{0}, []float64{0})
+	for _, s := range buf.Sample(10) {
+		if s.Transition.Reward != 1 {
+			t.Fatal("expected only new transition to be sampled")
+		}
+	}
+}
+
+func TestSumTree(t *testing.T) {
+	for size := 1; size < 8; size++ {
+		tree := newSumTree(size)
+		var total float64
+		for i := 0; i < size; i++ {
+			tree.Set(i, float64(i%2))
+			total += float64(i % 2)
+		}
+		if tree.Total() != total {
+			t.Errorf("size %d: expected total %f but got %f", size, total, tree.Total())
+		}
+		for x := 0.0; x < total; x += 0.25 {
+			leaf := tree.Find(x)
+			if tree.Get(leaf) == 0 {
+				t.Errorf("size %d: found zero leaf %d for %f", size, leaf, x)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package anyreplay
+
+import (
+	"math"
+
+	"github.com/unixpickle/anyrl"
+)
+
+// A Transition is a (possibly multi-step) transition in
+// an environment.
+//
+// The target for a Q-function at a Transition t is
+//
+//     t.Reward + discount^t.Steps * V(t.NextObs)
+//
+// where the second term is omitted if t.Done is true,
+// and where a discount of 0 stands for no discount (see
+// NStep.Discount).
+type Transition struct {
+	// Obs is the observation before the first action.
+	Obs []float64
+
+	// Action is the first action taken.
+	Action []float64
+
+	// Reward is the discounted sum of the rewards from
+	// every step of the transition.
+	Reward float64
+
+	// Steps is the number of rewards in Reward.
+	Steps int
+
+	// NextObs is the observation after the last step.
+	//
+	// This may be nil if Done is true.
+	NextObs []float64
+
+	// Done is true if the episode reached a terminal
+	// state during the transition.
+	// It is false if the episode was truncated.
+	Done bool
+}
+
+// NStep assembles n-step transitions from a stream of
+// single environment steps.
+//
+// An NStep should only be used for one environment at a
+// time.
+type NStep struct {
+	// Steps is the maximum number of environment steps per
+	// transition.
+	//
+	// If 0, single-step transitions are produced.
+	Steps int
+
+	// Discount is the reward discount factor.
+	//
+	// If 0, no discount is used, like for the Discount
+	// fields in anypg.
+	// To ignore all but the first reward, use Steps = 1
+	// instead.
+	Discount float64
+
+	pending []*Transition
+}
+
+// Step adds an environment step and returns any newly
+// completed transitions.
+//
+// The done and truncated arguments have the same meaning
+// as for anyrl.Env.Step and anyrl.EnvTruncated.
+// When done is true, every pending transition is
+// completed, each with fewer than Steps steps.
+// If the episode was truncated but nextObs is nil, then
+// the pending transitions cannot be bootstrapped and are
+// dropped.
+//
+// The slices passed to Step are not copied.
+func (n *NStep) Step(obs, action []float64, reward float64, nextObs []float64,
+	done, truncated bool) []*Transition {
+	n.pending = append(n.pending, &Transition{Obs: obs, Action: action})
+	for _, t := range n.pending {
+		t.Reward += n.discountPower(t.Steps) * reward
+		t.Steps++
+	}
+
+	if done {
+		res := n.pending
+		n.pending = nil
+		if truncated && nextObs == nil {
+			return nil
+		}
+		for _, t := range res {
+			t.NextObs = nextObs
+			t.Done = !truncated
+		}
+		return res
+	}
+
+	steps := n.Steps
+	if steps <= 0 {
+		steps = 1
+	}
+	if n.pending[0].Steps < steps {
+		return nil
+	}
+	t := n.pending[0]
+	n.pending = n.pending[1:]
+	t.NextObs = nextObs
+	return []*Transition{t}
+}
+
+// Reset discards any pending transitions.
+//
+// This should be called if an episode is abandoned before
+// it is done.
+func (n *NStep) Reset() {
+	n.pending = nil
+}
+
+func (n *NStep) discountPower(steps int) float64 {
+	if n.Discount == 0 {
+		return 1
+	}
+	return math.Pow(n.Discount, float64(steps))
+}
+
+// RolloutTransitions extracts n-step transitions from the
+// episodes in a RolloutSet.
+//
+// The steps and discount arguments are used like the
+// corresponding fields of NStep.
+// In particular, a discount of 0 means no discount.
+//
+// Truncated episodes are bootstrapped from their final
+// observation.
+func RolloutTransitions(r *anyrl.RolloutSet, steps int,
+	discount float64) []*Transition {
+	inputs := anyrl.TapeSeqs(r.Inputs, len(r.Rewards))
+	actions := anyrl.TapeSeqs(r.Actions, len(r.Rewards))
+
+	var res []*Transition
+	for i, rews := range r.Rewards {
+		ns := &NStep{Steps: steps, Discount: discount}
+		for t, rew := range rews {
+			done := t+1 == len(rews)
+			var nextObs []float64
+			if !done {
+				nextObs = inputs[i][t+1]
+			} else if r.FinalObs != nil {
+				nextObs = r.FinalObs[i]
+			}
+			truncated := done && r.EpisodeTruncated(i)
+			res = append(res, ns.Step(inputs[i][t], actions[i][t], rew, nextObs,
+				done, truncated)...)
+		}
+	}
+	return res
+}
+
+// AddRollouts adds the n-step transitions from a
+// RolloutSet to a Buffer.
+//
+// See RolloutTransitions for details.
+func AddRollouts(b Buffer, r *anyrl.RolloutSet, steps int, discount float64) {
+	for _, t := range RolloutTransitions(r, steps, discount) {
+		b.Add(t)
+	}
+}
@@ -0,0 +1,92 @@
+package anyreplay
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyrl"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestNStep(t *testing.T) {
+	ns := &NStep{Steps: 2, Discount: 0.5}
+	obs := [][]float64{{0}, {1}, {2}, {3}}
+	var res []*Transition
+	for i := 0; i < 3; i++ {
+		res = append(res, ns.Step(obs[i], []float64{float64(-i)}, float64(i+1),
+			obs[i+1], i == 2, false)...)
+	}
+	expected := []*Transition{
+		{Obs: obs[0], Action: []float64{0}, Reward: 2, Steps: 2, NextObs: obs[2]},
+		{Obs: obs[1], Action: []float64{-1}, Reward: 3.5, Steps: 2, NextObs: obs[3],
+			Done: true},
+		{Obs: obs[2], Action: []float64{-2}, Reward: 3, Steps: 1, NextObs: obs[3],
+			Done: true},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("expected %v but got %v", expected, res)
+	}
+
+	res = ns.Step(obs[0], []float64{0}, 1, obs[1], true, true)
+	if len(res) != 1 || res[0].Done || !reflect.DeepEqual(res[0].NextObs, obs[1]) {
+		t.Errorf("bad truncated transition: %v", res)
+	}
+	if res := ns.Step(obs[0], []float64{0}, 1, nil, true, true); len(res) != 0 {
+		t.Errorf("expected no transitions but got %v", res)
+	}
+}
+
+func TestRolloutTransitions(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	r := anyrl.TrajectoryRolloutSet(c, []*anyrl.Trajectory{
+		{
+			Observations: [][]float64{{1}, {2}, {3}},
+			Actions:      [][]float64{{1, 0}, {0, 1}, {1, 0}},
+			Rewards:      []float64{1, 2, 3},
+			Done:         true,
+			Truncated:    true,
+			FinalObs:     []float64{4},
+		},
+		{
+			Observations: [][]float64{{5}},
+			Actions:      [][]float64{{0, 1}},
+			Rewards:      []float64{-1},
+			Done:         true,
+		},
+	})
+	actual := RolloutTransitions(r, 2, 0)
+	expected := []*Transition{
+		{Obs: []float64{1}, Action: []float64{1, 0}, Reward: 3, Steps: 2,
+			NextObs: []float64{3}},
+		{Obs: []float64{2}, Action: []float64{0, 1}, Reward: 5, Steps: 2,
+			NextObs: []float64{4}},
+		{Obs: []float64{3}, Action: []float64{1, 0}, Reward: 3, Steps: 1,
+			NextObs: []float64{4}},
+		{Obs: []float64{5}, Action: []float64{0, 1}, Reward: -1, Steps: 1,
+			Done: true},
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d transitions but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		a := actual[i]
+		if !vecsClose(a.Obs, x.Obs) || !vecsClose(a.Action, x.Action) ||
+			!vecsClose(a.NextObs, x.NextObs) || a.Reward != x.Reward ||
+			a.Steps != x.Steps || a.Done != x.Done {
+			t.Errorf("transition %d: expected %v but got %v", i, x, a)
+		}
+	}
+}
+
+func vecsClose(v1, v2 []float64) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i, x := range v1 {
+		if math.Abs(x-v2[i]) > 1e-8 {
+			return false
+		}
+	}
+	return true
+}
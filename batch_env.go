@@ -0,0 +1,189 @@
+package anyrl
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+// A BatchEnv is a batch of environments which are reset
+// and stepped together.
+//
+// Observations and actions are packed into batches, where
+// each environment corresponds to one sequence.
+// This makes it possible to implement vectorized
+// simulators which step many environments at once.
+//
+// Use EnvBatch to create a BatchEnv from a list of Envs.
+type BatchEnv interface {
+	// NumEnvs returns the number of environments.
+	NumEnvs() int
+
+	// Reset resets every environment.
+	//
+	// All the environments are present in the resulting
+	// batch, which is created with the given creator.
+	Reset(c anyvec.Creator) (obs *anyseq.Batch, err error)
+
+	// Step steps every environment that is present in the
+	// action batch.
+	//
+	// The obs batch contains the environments which are
+	// not done.
+	// The rewards are packed like the actions, with one
+	// reward per present environment.
+	//
+	// The finalObs result contains the last observation
+	// for every environment whose episode was just
+	// truncated.
+	// It is indexed by environment and is nil for all
+	// other environments.
+	Step(actions *anyseq.Batch) (obs *anyseq.Batch, rewards []float64,
+		finalObs [][]float64, err error)
+}
+
+// SeedBatchEnv seeds a BatchEnv using seeds from gen.
+//
+// If e is a Seeder, it is seeded directly.
+// If e was created by EnvBatch, each Env which is a
+// Seeder gets its own seed.
+func SeedBatchEnv(e BatchEnv, gen *rand.Rand) {
+	if s, ok := e.(Seeder); ok {
+		s.Seed(gen.Int63())
+	} else if b, ok := e.(*envBatch); ok {
+		for _, env := range b.envs {
+			SeedEnv(env, gen.Int63())
+		}
+	}
+}
+
+type envBatch struct {
+	envs []Env
+}
+
+// EnvBatch creates a BatchEnv which steps the Envs in
+// parallel.
+//
+// The result implements CtxBatchEnv, using EnvStepCtx and
+// EnvResetCtx to step and reset the Envs.
+func EnvBatch(envs ...Env) BatchEnv {
+	return &envBatch{envs: envs}
+}
+
+func (e *envBatch) NumEnvs() int {
+	return len(e.envs)
+}
+
+func (e *envBatch) Reset(c anyvec.Creator) (*anyseq.Batch, error) {
+	return e.ResetCtx(context.Background(), c)
+}
+
+func (e *envBatch) Step(actions *anyseq.Batch) (*anyseq.Batch, []float64,
+	[][]float64, error) {
+	return e.StepCtx(context.Background(), actions)
+}
+
+func (e *envBatch) ResetCtx(ctx context.Context, c anyvec.Creator) (*anyseq.Batch,
+	error) {
+	initBatch := &anyseq.Batch{
+		Present: make([]bool, len(e.envs)),
+	}
+
+	var allObs []float64
+	for i, env := range e.envs {
+		obs, err := EnvResetCtx(ctx, env)
+		if err != nil {
+			return nil, err
+		}
+		initBatch.Present[i] = true
+		allObs = append(allObs, obs...)
+	}
+
+	initBatch.Packed = anyvec.Make(c, allObs)
+
+	return initBatch, nil
+}
+
+func (e *envBatch) StepCtx(ctx context.Context, actions *anyseq.Batch) (obs *anyseq.Batch,
+	rewards []float64, finalObs [][]float64, err error) {
+	c := actions.Packed.Creator()
+	obs = &anyseq.Batch{
+		Present: make([]bool, len(actions.Present)),
+	}
+	finalObs = make([][]float64, len(e.envs))
+	var splitActions [][]float64
+	var presentEnvs []Env
+
+	for i, action := range splitBatch(actions) {
+		if actions.Present[i] {
+			splitActions = append(splitActions, action)
+			presentEnvs = append(presentEnvs, e.envs[i])
+		}
+	}
+
+	obsVecs, rewards, dones, errs := batchStep(ctx, presentEnvs, splitActions)
+
+	var presentIdx int
+	var joinObs []float64
+	for i, pres := range actions.Present {
+		if !pres {
+			continue
+		}
+		obsVec, done, err := obsVecs[presentIdx], dones[presentIdx], errs[presentIdx]
+		presentIdx++
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !done {
+			obs.Present[i] = true
+			joinObs = append(joinObs, obsVec...)
+		} else if EnvTruncated(e.envs[i]) {
+			finalObs[i] = obsVec
+		}
+	}
+
+	obs.Packed = anyvec.Make(c, joinObs)
+
+	return
+}
+
+// splitBatch splits a packed batch into one vector per
+// sequence.
+// Vectors for absent sequences are nil.
+func splitBatch(batch *anyseq.Batch) [][]float64 {
+	res := make([][]float64, len(batch.Present))
+	numPresent := batch.NumPresent()
+	if numPresent == 0 {
+		return res
+	}
+	chunkSize := batch.Packed.Len() / numPresent
+	slice := batch.Packed.Creator().Float64Slice(batch.Packed.Data())
+	for i, pres := range batch.Present {
+		if pres {
+			res[i] = slice[:chunkSize]
+			slice = slice[chunkSize:]
+		}
+	}
+	return res
+}
+
+func batchStep(ctx context.Context, envs []Env, actions [][]float64) (obs [][]float64,
+	rewards []float64, done []bool, err []error) {
+	obs = make([][]float64, len(envs))
+	rewards = make([]float64, len(envs))
+	done = make([]bool, len(envs))
+	err = make([]error, len(envs))
+	var wg sync.WaitGroup
+	for i, e := range envs {
+		wg.Add(1)
+		go func(i int, e Env) {
+			defer wg.Done()
+			obs[i], rewards[i], done[i], err[i] = EnvStepCtx(ctx, e, actions[i])
+		}(i, e)
+	}
+	wg.Wait()
+	return
+}
@@ -0,0 +1,51 @@
+package anyrl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestEnvBatch(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	batch := EnvBatch(
+		&rnnTestEnv{RewardScale: 1, EpLen: 1, Observation: []float64{1}},
+		&rnnTestEnv{RewardScale: 2, EpLen: 2, Observation: []float64{2}},
+		&MaxStepsEnv{
+			Env:      &rnnTestEnv{RewardScale: 3, EpLen: 5, Observation: []float64{3}},
+			MaxSteps: 1,
+		},
+	)
+	if batch.NumEnvs() != 3 {
+		t.Fatalf("unexpected NumEnvs: %d", batch.NumEnvs())
+	}
+	obs, err := batch.Reset(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := c.Float64Slice(obs.Packed.Data()); !reflect.DeepEqual(actual,
+		[]float64{1, 2, 3}) {
+		t.Errorf("unexpected initial observations: %v", actual)
+	}
+
+	actions := &anyseq.Batch{
+		Packed:  anyvec.Make(c, []float64{0, 1, 0, 1, 0, 1}),
+		Present: obs.Present,
+	}
+	obs, rewards, finalObs, err := batch.Step(actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []bool{false, true, false}; !reflect.DeepEqual(obs.Present, expected) {
+		t.Errorf("expected present %v but got %v", expected, obs.Present)
+	}
+	if expected := []float64{1, 2, 3}; !reflect.DeepEqual(rewards, expected) {
+		t.Errorf("expected rewards %v but got %v", expected, rewards)
+	}
+	if expected := [][]float64{nil, nil, {3}}; !reflect.DeepEqual(finalObs, expected) {
+		t.Errorf("expected final observations %v but got %v", expected, finalObs)
+	}
+}
@@ -0,0 +1,483 @@
+package anyrl
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec"
+)
+
+// boundedEpsilon is used to keep outputs of bounded
+// distributions away from their bounds, where the log
+// densities may be infinite.
+const boundedEpsilon = 1e-6
+
+// Beta is a continuous action space with values sampled
+// from Beta distributions, rescaled to the range
+// [Low, High].
+//
+// For each component of the output, there is a parameter
+// for alpha and one for beta (in that order).
+// The parameters may be any values; they are fed into the
+// function 1+ln(1+exp(x)) to get alpha and beta.
+// As a result, the distributions are always unimodal.
+type Beta struct {
+	// Low and High are the bounds of the output.
+	// If they are equal, the range [-1, 1] is used.
+	Low  float64
+	High float64
+
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+}
+
+// Sample samples continuous values from the distribution.
+func (b Beta) Sample(params anyvec.Vector, batch int) anyvec.Vector {
+	c := params.Creator()
+	low, high := boundsOrDefault(b.Low, b.High)
+	paramSlice := c.Float64Slice(params.Data())
+	res := make([]float64, len(paramSlice)/2)
+	for i := range res {
+		alpha := 1 + softplus(paramSlice[2*i])
+		beta := 1 + softplus(paramSlice[2*i+1])
+		x := sampleGamma(b.Rand, alpha)
+		y := sampleGamma(b.Rand, beta)
+		res[i] = low + (high-low)*x/(x+y)
+	}
+	return c.MakeVectorData(c.MakeNumericList(res))
+}
+
+// Mode computes the modes of the distributions.
+//
+// For uniform distributions, the center of the range is
+// used.
+func (b Beta) Mode(params anyvec.Vector, batch int) anyvec.Vector {
+	c := params.Creator()
+	low, high := boundsOrDefault(b.Low, b.High)
+	paramSlice := c.Float64Slice(params.Data())
+	res := make([]float64, len(paramSlice)/2)
+	for i := range res {
+		alpha := 1 + softplus(paramSlice[2*i])
+		beta := 1 + softplus(paramSlice[2*i+1])
+		x := 0.5
+		if alpha+beta > 2 {
+			x = (alpha - 1) / (alpha + beta - 2)
+		}
+		res[i] = low + (high-low)*x
+	}
+	return c.MakeVectorData(c.MakeNumericList(res))
+}
+
+// LogProb computes the output log densities.
+//
+// Outputs at (or beyond) the bounds are treated as if
+// they were slightly inside the bounds.
+func (b Beta) LogProb(params anydiff.Res, output anyvec.Vector,
+	batch int) anydiff.Res {
+	c := output.Creator()
+	low, high := boundsOrDefault(b.Low, b.High)
+
+	outSlice := c.Float64Slice(output.Data())
+	logX := make([]float64, len(outSlice))
+	logComp := make([]float64, len(outSlice))
+	for i, out := range outSlice {
+		x := clipFloat((out-low)/(high-low), boundedEpsilon, 1-boundedEpsilon)
+		logX[i] = math.Log(x)
+		logComp[i] = math.Log(1 - x)
+	}
+
+	return anydiff.Pool(params, func(params anydiff.Res) anydiff.Res {
+		alpha, beta := b.alphaBeta(params)
+		return anydiff.Pool(alpha, func(alpha anydiff.Res) anydiff.Res {
+			return anydiff.Pool(beta, func(beta anydiff.Res) anydiff.Res {
+				negOne := c.MakeNumeric(-1)
+				logDensity := anydiff.Sub(
+					anydiff.Add(
+						anydiff.Mul(anydiff.AddScalar(alpha, negOne),
+							anydiff.NewConst(c.MakeVectorData(c.MakeNumericList(logX)))),
+						anydiff.Mul(anydiff.AddScalar(beta, negOne),
+							anydiff.NewConst(c.MakeVectorData(c.MakeNumericList(logComp)))),
+					),
+					logBetaFunc(alpha, beta),
+				)
+				return anydiff.SumCols(&anydiff.Matrix{
+					Data: anydiff.AddScalar(logDensity,
+						c.MakeNumeric(-math.Log(high-low))),
+					Rows: batch,
+					Cols: alpha.Output().Len() / batch,
+				})
+			})
+		})
+	})
+}
+
+// KL computes the KL divergences between two batches of
+// distributions.
+//
+// The log-gamma and digamma functions are approximated
+// with asymptotic series, which are accurate to roughly
+// 1e-8 for all valid parameters.
+func (b Beta) KL(params1, params2 anydiff.Res, batch int) anydiff.Res {
+	return anydiff.Pool(params1, func(params1 anydiff.Res) anydiff.Res {
+		return anydiff.Pool(params2, func(params2 anydiff.Res) anydiff.Res {
+			a1, b1 := b.alphaBeta(params1)
+			a2, b2 := b.alphaBeta(params2)
+			return poolAll([]anydiff.Res{a1, b1, a2, b2}, func(x []anydiff.Res) anydiff.Res {
+				a1, b1, a2, b2 := x[0], x[1], x[2], x[3]
+				sum1 := anydiff.Add(a1, b1)
+				return anydiff.Pool(sum1, func(sum1 anydiff.Res) anydiff.Res {
+					// ln(B(a2,b2)/B(a1,b1)) + (a1-a2)*psi(a1) +
+					// (b1-b2)*psi(b1) + (a2-a1+b2-b1)*psi(a1+b1)
+					kl := anydiff.Add(
+						anydiff.Sub(logBetaFunc(a2, b2), logBetaFunc(a1, b1)),
+						anydiff.Add(
+							anydiff.Add(
+								anydiff.Mul(anydiff.Sub(a1, a2), digamma(a1)),
+								anydiff.Mul(anydiff.Sub(b1, b2), digamma(b1)),
+							),
+							anydiff.Mul(anydiff.Sub(anydiff.Add(a2, b2), sum1),
+								digamma(sum1)),
+						),
+					)
+					return anydiff.SumCols(&anydiff.Matrix{
+						Data: kl,
+						Rows: batch,
+						Cols: a1.Output().Len() / batch,
+					})
+				})
+			})
+		})
+	})
+}
+
+// Entropy computes the differential entropy for the
+// batches of distributions.
+//
+// See KL for details on the approximations used.
+func (b Beta) Entropy(params anydiff.Res, batch int) anydiff.Res {
+	c := params.Output().Creator()
+	low, high := boundsOrDefault(b.Low, b.High)
+	return anydiff.Pool(params, func(params anydiff.Res) anydiff.Res {
+		alpha, beta := b.alphaBeta(params)
+		return poolAll([]anydiff.Res{alpha, beta}, func(x []anydiff.Res) anydiff.Res {
+			alpha, beta := x[0], x[1]
+			negOne := c.MakeNumeric(-1)
+			sum := anydiff.Add(alpha, beta)
+			return anydiff.Pool(sum, func(sum anydiff.Res) anydiff.Res {
+				// ln(B(a,b)) - (a-1)*psi(a) - (b-1)*psi(b) +
+				// (a+b-2)*psi(a+b)
+				entropy := anydiff.Add(
+					anydiff.Sub(
+						logBetaFunc(alpha, beta),
+						anydiff.Add(
+							anydiff.Mul(anydiff.AddScalar(alpha, negOne), digamma(alpha)),
+							anydiff.Mul(anydiff.AddScalar(beta, negOne), digamma(beta)),
+						),
+					),
+					anydiff.Mul(anydiff.AddScalar(sum, c.MakeNumeric(-2)), digamma(sum)),
+				)
+				return anydiff.SumCols(&anydiff.Matrix{
+					Data: anydiff.AddScalar(entropy, c.MakeNumeric(math.Log(high-low))),
+					Rows: batch,
+					Cols: alpha.Output().Len() / batch,
+				})
+			})
+		})
+	})
+}
+
+func (b Beta) alphaBeta(params anydiff.Res) (alpha, beta anydiff.Res) {
+	c := params.Output().Creator()
+	alphaParams, betaParams := splitPairs(params)
+	alpha = anydiff.AddScalar(softplusRes(alphaParams), c.MakeNumeric(1))
+	beta = anydiff.AddScalar(softplusRes(betaParams), c.MakeNumeric(1))
+	return
+}
+
+// TanhGaussian is a continuous action space with values
+// sampled from a Gaussian, squashed with tanh, and then
+// rescaled to the range [Low, High].
+//
+// The parameters are the same as for Gaussian: a mean and
+// a log variance for each component, before squashing.
+type TanhGaussian struct {
+	// Low and High are the bounds of the output.
+	// If they are equal, the range [-1, 1] is used.
+	Low  float64
+	High float64
+
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+}
+
+// Sample samples continuous values from the distribution.
+func (t TanhGaussian) Sample(params anyvec.Vector, batch int) anyvec.Vector {
+	return t.squash(Gaussian{Rand: t.Rand}.Sample(params, batch))
+}
+
+// Mode produces the squashed means of the distributions.
+//
+// Strictly speaking, this is the mode of the underlying
+// Gaussian rather than of the squashed distribution, but
+// it is the conventional deterministic action.
+func (t TanhGaussian) Mode(params anyvec.Vector, batch int) anyvec.Vector {
+	return t.squash(Gaussian{}.Mode(params, batch))
+}
+
+// LogProb computes the output log densities.
+//
+// This includes the log determinant of the Jacobian of
+// the squashing function.
+// Outputs at (or beyond) the bounds are treated as if
+// they were slightly inside the bounds.
+func (t TanhGaussian) LogProb(params anydiff.Res, output anyvec.Vector,
+	batch int) anydiff.Res {
+	c := output.Creator()
+	low, high := boundsOrDefault(t.Low, t.High)
+
+	outSlice := c.Float64Slice(output.Data())
+	unsquashed := make([]float64, len(outSlice))
+	var logDets []float64
+	for i, out := range outSlice {
+		y := clipFloat(2*(out-low)/(high-low)-1, boundedEpsilon-1, 1-boundedEpsilon)
+		unsquashed[i] = math.Atanh(y)
+		logDets = append(logDets, math.Log((high-low)/2*(1-y*y)))
+	}
+	logDetSums := make([]float64, batch)
+	for i, logDet := range logDets {
+		logDetSums[i/(len(logDets)/batch)] += logDet
+	}
+
+	gaussianProbs := Gaussian{}.LogProb(params,
+		c.MakeVectorData(c.MakeNumericList(unsquashed)), batch)
+	return anydiff.Sub(gaussianProbs,
+		anydiff.NewConst(c.MakeVectorData(c.MakeNumericList(logDetSums))))
+}
+
+// KL computes the KL divergences between two batches of
+// distributions.
+//
+// Since squashing is invertible, this is exactly the KL
+// divergence between the underlying Gaussians.
+func (t TanhGaussian) KL(params1, params2 anydiff.Res, batch int) anydiff.Res {
+	return Gaussian{}.KL(params1, params2, batch)
+}
+
+// Entropy approximates the differential entropy for the
+// batches of distributions.
+//
+// The exact entropy is the Gaussian entropy plus the
+// expected log determinant of the squashing function's
+// Jacobian, which has no closed form.
+// This approximates the expectation by evaluating the
+// log determinant at the mean.
+// Thus, the approximation is exact as the variance goes
+// to zero, and it penalizes means that saturate the tanh.
+func (t TanhGaussian) Entropy(params anydiff.Res, batch int) anydiff.Res {
+	c := params.Output().Creator()
+	low, high := boundsOrDefault(t.Low, t.High)
+	return anydiff.Pool(params, func(params anydiff.Res) anydiff.Res {
+		mean, _ := splitPairs(params)
+		return anydiff.Pool(mean, func(mean anydiff.Res) anydiff.Res {
+			// 1-tanh(x)^2 = 4*sigmoid(2x)*sigmoid(-2x)
+			logDet := anydiff.AddScalar(
+				anydiff.Add(
+					anydiff.LogSigmoid(anydiff.Scale(mean, c.MakeNumeric(2))),
+					anydiff.LogSigmoid(anydiff.Scale(mean, c.MakeNumeric(-2))),
+				),
+				c.MakeNumeric(math.Log(4)+math.Log((high-low)/2)),
+			)
+			return anydiff.Add(
+				Gaussian{}.Entropy(params, batch),
+				anydiff.SumCols(&anydiff.Matrix{
+					Data: logDet,
+					Rows: batch,
+					Cols: mean.Output().Len() / batch,
+				}),
+			)
+		})
+	})
+}
+
+func (t TanhGaussian) squash(vec anyvec.Vector) anyvec.Vector {
+	c := vec.Creator()
+	low, high := boundsOrDefault(t.Low, t.High)
+	anyvec.Tanh(vec)
+	vec.AddScalar(c.MakeNumeric(1))
+	vec.Scale(c.MakeNumeric((high - low) / 2))
+	vec.AddScalar(c.MakeNumeric(low))
+	return vec
+}
+
+// logBetaFunc computes ln(B(a, b)) for a, b >= 1.
+func logBetaFunc(a, b anydiff.Res) anydiff.Res {
+	return anydiff.Sub(anydiff.Add(logGamma(a), logGamma(b)),
+		logGamma(anydiff.Add(a, b)))
+}
+
+// gammaShift is the number of times the recurrence
+// relation is applied before an asymptotic series is used
+// in logGamma and digamma.
+const gammaShift = 6
+
+// logGamma approximates ln(Gamma(x)) for x >= 1.
+//
+// It is built out of differentiable primitives, so it
+// works with any anyvec.Creator (e.g. for forward-mode
+// differentiation).
+func logGamma(x anydiff.Res) anydiff.Res {
+	c := x.Output().Creator()
+	return anydiff.Pool(x, func(x anydiff.Res) anydiff.Res {
+		// ln(Gamma(x)) = ln(Gamma(x+n)) - ln(x) - ... - ln(x+n-1)
+		logProd := anydiff.Log(x)
+		for i := 1; i < gammaShift; i++ {
+			logProd = anydiff.Add(logProd, anydiff.Log(anydiff.AddScalar(x,
+				c.MakeNumeric(float64(i)))))
+		}
+		shifted := anydiff.AddScalar(x, c.MakeNumeric(gammaShift))
+		return anydiff.Pool(shifted, func(y anydiff.Res) anydiff.Res {
+			return anydiff.Pool(anydiff.Pow(y, c.MakeNumeric(-1)),
+				func(inv anydiff.Res) anydiff.Res {
+					// Stirling's series:
+					// (y-1/2)*ln(y) - y + ln(2*pi)/2 + 1/(12y) -
+					// 1/(360y^3) + 1/(1260y^5)
+					inv2 := anydiff.Square(inv)
+					series := anydiff.Mul(inv, anydiff.AddScalar(
+						anydiff.Mul(inv2, anydiff.AddScalar(
+							anydiff.Scale(inv2, c.MakeNumeric(1.0/1260)),
+							c.MakeNumeric(-1.0/360),
+						)),
+						c.MakeNumeric(1.0/12),
+					))
+					stirling := anydiff.Add(
+						anydiff.Sub(
+							anydiff.Mul(anydiff.AddScalar(y, c.MakeNumeric(-0.5)),
+								anydiff.Log(y)),
+							y,
+						),
+						anydiff.AddScalar(series, c.MakeNumeric(0.5*math.Log(2*math.Pi))),
+					)
+					return anydiff.Sub(stirling, logProd)
+				})
+		})
+	})
+}
+
+// digamma approximates the digamma function for x >= 1.
+//
+// See logGamma for details.
+func digamma(x anydiff.Res) anydiff.Res {
+	c := x.Output().Creator()
+	return anydiff.Pool(x, func(x anydiff.Res) anydiff.Res {
+		// psi(x) = psi(x+n) - 1/x - ... - 1/(x+n-1)
+		invSum := anydiff.Pow(x, c.MakeNumeric(-1))
+		for i := 1; i < gammaShift; i++ {
+			invSum = anydiff.Add(invSum, anydiff.Pow(anydiff.AddScalar(x,
+				c.MakeNumeric(float64(i))), c.MakeNumeric(-1)))
+		}
+		shifted := anydiff.AddScalar(x, c.MakeNumeric(gammaShift))
+		return anydiff.Pool(shifted, func(y anydiff.Res) anydiff.Res {
+			return anydiff.Pool(anydiff.Pow(y, c.MakeNumeric(-1)),
+				func(inv anydiff.Res) anydiff.Res {
+					// ln(y) - 1/(2y) - 1/(12y^2) + 1/(120y^4) -
+					// 1/(252y^6)
+					inv2 := anydiff.Square(inv)
+					series := anydiff.Mul(inv2, anydiff.AddScalar(
+						anydiff.Mul(inv2, anydiff.AddScalar(
+							anydiff.Scale(inv2, c.MakeNumeric(-1.0/252)),
+							c.MakeNumeric(1.0/120),
+						)),
+						c.MakeNumeric(-1.0/12),
+					))
+					asymptotic := anydiff.Add(
+						anydiff.Sub(anydiff.Log(y), anydiff.Scale(inv, c.MakeNumeric(0.5))),
+						series,
+					)
+					return anydiff.Sub(asymptotic, invSum)
+				})
+		})
+	})
+}
+
+// splitPairs splits a vector of the form
+//
+//     <a1, b1, a2, b2, ...>
+//
+// into two vectors <a1, a2, ...> and <b1, b2, ...>.
+func splitPairs(params anydiff.Res) (first, second anydiff.Res) {
+	halfLen := params.Output().Len() / 2
+	mat := &anydiff.Matrix{Data: params, Rows: halfLen, Cols: 2}
+	tr := anydiff.Transpose(mat)
+	first = anydiff.Slice(tr.Data, 0, halfLen)
+	second = anydiff.Slice(tr.Data, halfLen, halfLen*2)
+	return
+}
+
+// poolAll pools every Res in rs and passes the pooled
+// results to f.
+func poolAll(rs []anydiff.Res, f func(pooled []anydiff.Res) anydiff.Res) anydiff.Res {
+	if len(rs) == 0 {
+		return f(nil)
+	}
+	return anydiff.Pool(rs[0], func(first anydiff.Res) anydiff.Res {
+		return poolAll(rs[1:], func(rest []anydiff.Res) anydiff.Res {
+			return f(append([]anydiff.Res{first}, rest...))
+		})
+	})
+}
+
+// softplusRes computes ln(1+exp(x)) in a numerically
+// stable way.
+func softplusRes(x anydiff.Res) anydiff.Res {
+	c := x.Output().Creator()
+	return anydiff.Scale(anydiff.LogSigmoid(anydiff.Scale(x, c.MakeNumeric(-1))),
+		c.MakeNumeric(-1))
+}
+
+func softplus(x float64) float64 {
+	if x > 30 {
+		return x
+	}
+	return math.Log1p(math.Exp(x))
+}
+
+// sampleGamma samples from a Gamma distribution with the
+// given shape (which must be at least 1) and a scale of 1.
+//
+// If gen is nil, the global generator is used.
+func sampleGamma(gen *rand.Rand, shape float64) float64 {
+	normFloat, uniform := rand.NormFloat64, rand.Float64
+	if gen != nil {
+		normFloat, uniform = gen.NormFloat64, gen.Float64
+	}
+
+	// Marsaglia and Tsang's method.
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := normFloat()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := uniform()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+func boundsOrDefault(low, high float64) (float64, float64) {
+	if low == high {
+		return -1, 1
+	}
+	return low, high
+}
+
+func clipFloat(x, min, max float64) float64 {
+	return math.Max(min, math.Min(max, x))
+}
@@ -0,0 +1,170 @@
+package anyrl
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestLogGammaDigamma(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	inputs := []float64{1, 1.5, 2.3, 7, 31.7, 1000}
+	in := anydiff.NewConst(c.MakeVectorData(inputs))
+	logGammas := logGamma(in).Output().Data().([]float64)
+	digammas := digamma(in).Output().Data().([]float64)
+	for i, x := range inputs {
+		expected, _ := math.Lgamma(x)
+		if math.Abs(logGammas[i]-expected) > 1e-8 {
+			t.Errorf("lgamma(%f): expected %f but got %f", x, expected, logGammas[i])
+		}
+		lgPlus, _ := math.Lgamma(x + 1e-5)
+		lgMinus, _ := math.Lgamma(x - 1e-5)
+		expected = (lgPlus - lgMinus) / 2e-5
+		if math.Abs(digammas[i]-expected) > 1e-5 {
+			t.Errorf("digamma(%f): expected %f but got %f", x, expected, digammas[i])
+		}
+	}
+}
+
+func TestBetaSample(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	b := Beta{Low: -2, High: 3}
+	params := c.MakeVectorData([]float64{0.5, -1, -0.3, 2})
+	alpha1, beta1 := 1+softplus(0.5), 1+softplus(-1)
+	alpha2, beta2 := 1+softplus(-0.3), 1+softplus(2)
+
+	const numSamples = 100000
+	var sum1, sum2 float64
+	for i := 0; i < numSamples; i++ {
+		sample := b.Sample(params, 1).Data().([]float64)
+		for _, x := range sample {
+			if x < -2 || x > 3 {
+				t.Fatalf("sample out of bounds: %f", x)
+			}
+		}
+		sum1 += sample[0]
+		sum2 += sample[1]
+	}
+	expected1 := -2 + 5*alpha1/(alpha1+beta1)
+	expected2 := -2 + 5*alpha2/(alpha2+beta2)
+	if math.Abs(sum1/numSamples-expected1) > 1e-2 {
+		t.Errorf("expected mean %f but got %f", expected1, sum1/numSamples)
+	}
+	if math.Abs(sum2/numSamples-expected2) > 1e-2 {
+		t.Errorf("expected mean %f but got %f", expected2, sum2/numSamples)
+	}
+}
+
+func TestBetaLogProb(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	b := Beta{Low: -2, High: 3}
+	params := c.MakeVectorData([]float64{0.5, -1, -0.3, 2})
+	output := c.MakeVectorData([]float64{0.5, -1.5})
+	actual := b.LogProb(anydiff.NewConst(params), output, 1).Output().Data().([]float64)
+
+	expected := betaLogDensity(1+softplus(0.5), 1+softplus(-1), 0.5) +
+		betaLogDensity(1+softplus(-0.3), 1+softplus(2), 0.1) - 2*math.Log(5)
+	if math.Abs(actual[0]-expected) > 1e-8 {
+		t.Errorf("expected %f but got %f", expected, actual[0])
+	}
+}
+
+func TestBetaKL(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	b := Beta{}
+	params1 := c.MakeVectorData([]float64{0.5, -1, 3, 0.2})
+	params2 := c.MakeVectorData([]float64{-0.3, 2, 3, 0.2})
+	actual := b.KL(anydiff.NewConst(params1), anydiff.NewConst(params2),
+		2).Output().Data().([]float64)
+
+	a1, b1 := 1+softplus(0.5), 1+softplus(-1)
+	a2, b2 := 1+softplus(-0.3), 1+softplus(2)
+	expected := numericIntegral(func(x float64) float64 {
+		logP := betaLogDensity(a1, b1, x)
+		return math.Exp(logP) * (logP - betaLogDensity(a2, b2, x))
+	})
+	if math.Abs(actual[0]-expected) > 1e-4 {
+		t.Errorf("expected %f but got %f", expected, actual[0])
+	}
+	if math.Abs(actual[1]) > 1e-8 {
+		t.Errorf("expected 0 but got %f", actual[1])
+	}
+}
+
+func TestBetaEntropy(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	b := Beta{Low: 0, High: 2}
+	params := c.MakeVectorData([]float64{0.5, -1})
+	actual := b.Entropy(anydiff.NewConst(params), 1).Output().Data().([]float64)
+
+	alpha, beta := 1+softplus(0.5), 1+softplus(-1)
+	expected := math.Log(2) - numericIntegral(func(x float64) float64 {
+		logP := betaLogDensity(alpha, beta, x)
+		return math.Exp(logP) * logP
+	})
+	if math.Abs(actual[0]-expected) > 1e-4 {
+		t.Errorf("expected %f but got %f", expected, actual[0])
+	}
+}
+
+func TestTanhGaussianSample(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	g := TanhGaussian{Low: 1, High: 4}
+	params := c.MakeVectorData([]float64{5, 2, -0.3, -1})
+	for i := 0; i < 1000; i++ {
+		for _, x := range g.Sample(params, 1).Data().([]float64) {
+			if x < 1 || x > 4 {
+				t.Fatalf("sample out of bounds: %f", x)
+			}
+		}
+	}
+}
+
+func TestTanhGaussianLogProb(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	g := TanhGaussian{Low: 1, High: 4}
+	params := anydiff.NewConst(c.MakeVectorData([]float64{0.7, -0.5}))
+	total := numericIntegral(func(x float64) float64 {
+		output := c.MakeVectorData([]float64{1 + 3*x})
+		logProb := g.LogProb(params, output, 1).Output().Data().([]float64)[0]
+		return 3 * math.Exp(logProb)
+	})
+	if math.Abs(total-1) > 1e-3 {
+		t.Errorf("density should integrate to 1 but got %f", total)
+	}
+}
+
+func TestTanhGaussianEntropy(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	g := TanhGaussian{}
+	params := anydiff.NewConst(c.MakeVectorData([]float64{0.3, -8}))
+	actual := g.Entropy(params, 1).Output().Data().([]float64)[0]
+
+	// With a tiny variance, the approximation is nearly
+	// exact: a Gaussian with stddev sigma*(1-tanh(mean)^2).
+	stddev := math.Exp(-4) * (1 - math.Pow(math.Tanh(0.3), 2))
+	expected := 0.5 * (1 + math.Log(2*math.Pi*stddev*stddev))
+	if math.Abs(actual-expected) > 1e-8 {
+		t.Errorf("expected %f but got %f", expected, actual)
+	}
+}
+
+func betaLogDensity(alpha, beta, x float64) float64 {
+	lgA, _ := math.Lgamma(alpha)
+	lgB, _ := math.Lgamma(beta)
+	lgAB, _ := math.Lgamma(alpha + beta)
+	return (alpha-1)*math.Log(x) + (beta-1)*math.Log(1-x) - lgA - lgB + lgAB
+}
+
+// numericIntegral integrates f over (0, 1) using the
+// midpoint rule.
+func numericIntegral(f func(x float64) float64) float64 {
+	const numSteps = 100000
+	var sum float64
+	for i := 0; i < numSteps; i++ {
+		sum += f((float64(i) + 0.5) / numSteps)
+	}
+	return sum / numSteps
+}
@@ -0,0 +1,213 @@
+package anyrl
+
+import (
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/lazyseq"
+)
+
+// Chunker splits the episodes in a RolloutSet into
+// fixed-length windows for truncated backpropagation
+// through time.
+//
+// Each window is preceded by up to BurnIn timesteps from
+// the same episode.
+// Running an RNN over the burn-in timesteps recomputes an
+// approximation of the RNN state at the start of the
+// window, without requiring the state to be stored.
+// With a negative BurnIn, the burn-in reaches back to the
+// start of the episode, so the state is recomputed
+// exactly, at the cost of re-running the earlier
+// timesteps for every window.
+type Chunker struct {
+	// WindowSize is the maximum number of timesteps per
+	// window, not including burn-in.
+	WindowSize int
+
+	// BurnIn is the maximum number of burn-in timesteps
+	// before each window.
+	//
+	// If BurnIn is negative, every earlier timestep of the
+	// episode is used for burn-in.
+	// Even then, episodes which continue from a previous
+	// RolloutSet (e.g. from StreamRoller) start from the
+	// RNN's start state rather than from the state they
+	// were recorded with.
+	BurnIn int
+
+	// These functions are called to produce tapes for the
+	// windows.
+	//
+	// For nil fields, lazyseq.ReferenceTape is used.
+	MakeInputTape    TapeMaker
+	MakeActionTape   TapeMaker
+	MakeAgentOutTape TapeMaker
+}
+
+// Chunks stores the result of splitting a RolloutSet into
+// windows.
+type Chunks struct {
+	// Full is the original RolloutSet.
+	Full *RolloutSet
+
+	// Rollouts contains one episode per window, including
+	// the burn-in timesteps.
+	//
+	// Windows which are cut off before the end of their
+	// episode are marked as truncated, and their FinalObs
+	// is the next observation in the episode.
+	// Windows which do not start at the beginning of an
+	// episode are marked as continued.
+	Rollouts *RolloutSet
+
+	windows []chunkWindow
+}
+
+type chunkWindow struct {
+	Episode   int
+	BurnStart int
+	Start     int
+	End       int
+}
+
+// Chunk splits the episodes of r into windows.
+//
+// The windows are ordered by episode, and then
+// chronologically.
+func (c *Chunker) Chunk(r *RolloutSet) *Chunks {
+	if c.WindowSize <= 0 {
+		panic("window size must be positive")
+	}
+	res := &Chunks{Full: r}
+	for epIdx, rews := range r.Rewards {
+		for start := 0; start < len(rews); start += c.WindowSize {
+			var burnStart int
+			if c.BurnIn >= 0 && start > c.BurnIn {
+				burnStart = start - c.BurnIn
+			}
+			end := start + c.WindowSize
+			if end > len(rews) {
+				end = len(rews)
+			}
+			res.windows = append(res.windows, chunkWindow{
+				Episode:   epIdx,
+				BurnStart: burnStart,
+				Start:     start,
+				End:       end,
+			})
+		}
+	}
+
+	creator := r.Creator()
+	inputs := TapeSeqs(r.Inputs, len(r.Rewards))
+	rollouts := &RolloutSet{
+		Inputs:  res.splitSeqs(creator, inputs, c.MakeInputTape),
+		Actions: res.splitSeqs(creator, TapeSeqs(r.Actions, len(r.Rewards)), c.MakeActionTape),
+		Rewards: res.split(r.Rewards, false),
+	}
+	if r.AgentOuts != nil {
+		rollouts.AgentOuts = res.splitSeqs(creator, TapeSeqs(r.AgentOuts, len(r.Rewards)),
+			c.MakeAgentOutTape)
+	}
+	for _, w := range res.windows {
+		var truncated bool
+		var finalObs []float64
+		if w.End < len(r.Rewards[w.Episode]) {
+			truncated = true
+			finalObs = inputs[w.Episode][w.End]
+		} else if r.EpisodeTruncated(w.Episode) {
+			truncated = true
+			finalObs = r.FinalObs[w.Episode]
+		}
+		rollouts.Truncated = append(rollouts.Truncated, truncated)
+		rollouts.FinalObs = append(rollouts.FinalObs, finalObs)
+		rollouts.Continued = append(rollouts.Continued,
+			w.BurnStart > 0 || r.EpisodeContinued(w.Episode))
+	}
+	res.Rollouts = rollouts
+
+	return res
+}
+
+// BurnIn returns the number of burn-in timesteps at the
+// start of the window with the given index.
+func (c *Chunks) BurnIn(idx int) int {
+	return c.windows[idx].Start - c.windows[idx].BurnStart
+}
+
+// NumWindowSteps returns the total number of timesteps
+// in the windows, not including burn-in timesteps.
+func (c *Chunks) NumWindowSteps() int {
+	var res int
+	for _, w := range c.windows {
+		res += w.End - w.Start
+	}
+	return res
+}
+
+// SplitRewards splits per-timestep values for the full
+// episodes (e.g. judgements from an ActionJudger) into
+// values for the windows.
+//
+// The values for burn-in timesteps are set to 0, so that
+// burn-in timesteps have no effect on policy gradients.
+func (c *Chunks) SplitRewards(r Rewards) Rewards {
+	return c.split(r, true)
+}
+
+// SplitTape is like SplitRewards, but for tapes (e.g.
+// advantages from anypg.PPO).
+func (c *Chunks) SplitTape(t lazyseq.Tape) lazyseq.Tape {
+	creator := t.Creator()
+	seqs := TapeSeqs(t, len(c.Full.Rewards))
+	var res [][][]float64
+	for _, w := range c.windows {
+		var seq [][]float64
+		for i, vec := range seqs[w.Episode][w.BurnStart:w.End] {
+			if i < w.Start-w.BurnStart {
+				vec = make([]float64, len(vec))
+			}
+			seq = append(seq, vec)
+		}
+		res = append(res, seq)
+	}
+	return vectorSeqTape(creator, res)
+}
+
+// Mask produces a tape with a 1 at every window timestep
+// and a 0 at every burn-in timestep.
+func (c *Chunks) Mask() lazyseq.Tape {
+	ones := make(Rewards, len(c.Full.Rewards))
+	for i, rews := range c.Full.Rewards {
+		ones[i] = make([]float64, len(rews))
+		for j := range ones[i] {
+			ones[i][j] = 1
+		}
+	}
+	return c.SplitRewards(ones).Tape(c.Full.Creator())
+}
+
+func (c *Chunks) split(r Rewards, zeroBurnIn bool) Rewards {
+	var res Rewards
+	for _, w := range c.windows {
+		seq := append([]float64{}, r[w.Episode][w.BurnStart:w.End]...)
+		if zeroBurnIn {
+			for i := 0; i < w.Start-w.BurnStart; i++ {
+				seq[i] = 0
+			}
+		}
+		res = append(res, seq)
+	}
+	return res
+}
+
+func (c *Chunks) splitSeqs(creator anyvec.Creator, seqs [][][]float64,
+	maker TapeMaker) lazyseq.Tape {
+	var res [][][]float64
+	for _, w := range c.windows {
+		res = append(res, seqs[w.Episode][w.BurnStart:w.End])
+	}
+	tape, writer := makeTape(creator, maker)
+	writeVectorSeqs(creator, writer, res)
+	close(writer)
+	return tape
+}
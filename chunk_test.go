@@ -0,0 +1,106 @@
+package anyrl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestChunker(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	trajs := []*Trajectory{
+		{
+			Observations: [][]float64{{0}, {1}, {2}, {3}, {4}},
+			Actions:      [][]float64{{0}, {1}, {2}, {3}, {4}},
+			Rewards:      []float64{0, 1, 2, 3, 4},
+			Done:         true,
+		},
+		{
+			Observations: [][]float64{{10}, {11}},
+			Actions:      [][]float64{{10}, {11}},
+			Rewards:      []float64{10, 11},
+			Done:         true,
+			Truncated:    true,
+			FinalObs:     []float64{12},
+		},
+	}
+	full := TrajectoryRolloutSet(c, trajs)
+	chunks := (&Chunker{WindowSize: 2, BurnIn: 1}).Chunk(full)
+
+	expectedRewards := Rewards{{0, 1}, {1, 2, 3}, {3, 4}, {10, 11}}
+	if !reflect.DeepEqual(chunks.Rollouts.Rewards, expectedRewards) {
+		t.Errorf("expected rewards %v but got %v", expectedRewards, chunks.Rollouts.Rewards)
+	}
+	expectedTruncated := []bool{true, true, false, true}
+	if !reflect.DeepEqual(chunks.Rollouts.Truncated, expectedTruncated) {
+		t.Errorf("expected truncated %v but got %v", expectedTruncated,
+			chunks.Rollouts.Truncated)
+	}
+	expectedFinal := [][]float64{{2}, {4}, nil, {12}}
+	if !reflect.DeepEqual(chunks.Rollouts.FinalObs, expectedFinal) {
+		t.Errorf("expected final obs %v but got %v", expectedFinal,
+			chunks.Rollouts.FinalObs)
+	}
+	expectedContinued := []bool{false, true, true, false}
+	if !reflect.DeepEqual(chunks.Rollouts.Continued, expectedContinued) {
+		t.Errorf("expected continued %v but got %v", expectedContinued,
+			chunks.Rollouts.Continued)
+	}
+	for i, expected := range []int{0, 1, 1, 0} {
+		if actual := chunks.BurnIn(i); actual != expected {
+			t.Errorf("window %d: expected burn-in %d but got %d", i, expected, actual)
+		}
+	}
+
+	inputs := TapeSeqs(chunks.Rollouts.Inputs, 4)
+	expectedInputs := [][][]float64{
+		{{0}, {1}},
+		{{1}, {2}, {3}},
+		{{3}, {4}},
+		{{10}, {11}},
+	}
+	if !reflect.DeepEqual(inputs, expectedInputs) {
+		t.Errorf("expected inputs %v but got %v", expectedInputs, inputs)
+	}
+
+	split := chunks.SplitRewards(full.Rewards)
+	expectedSplit := Rewards{{0, 1}, {0, 2, 3}, {0, 4}, {10, 11}}
+	if !reflect.DeepEqual(split, expectedSplit) {
+		t.Errorf("expected split rewards %v but got %v", expectedSplit, split)
+	}
+	splitTape := TapeSeqs(chunks.SplitTape(full.Rewards.Tape(c)), 4)
+	if !reflect.DeepEqual(splitTape, TapeSeqs(expectedSplit.Tape(c), 4)) {
+		t.Errorf("unexpected split tape: %v", splitTape)
+	}
+	mask := TapeSeqs(chunks.Mask(), 4)
+	if !reflect.DeepEqual(mask, TapeSeqs(Rewards{{1, 1}, {0, 1, 1}, {0, 1}, {1, 1}}.Tape(c), 4)) {
+		t.Errorf("unexpected mask: %v", mask)
+	}
+}
+
+func TestChunkerFullBurnIn(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	full := TrajectoryRolloutSet(c, []*Trajectory{
+		{
+			Observations: [][]float64{{0}, {1}, {2}, {3}, {4}},
+			Actions:      [][]float64{{0}, {1}, {2}, {3}, {4}},
+			Rewards:      []float64{0, 1, 2, 3, 4},
+			Done:         true,
+		},
+	})
+	chunks := (&Chunker{WindowSize: 2, BurnIn: -1}).Chunk(full)
+
+	expectedRewards := Rewards{{0, 1}, {0, 1, 2, 3}, {0, 1, 2, 3, 4}}
+	if !reflect.DeepEqual(chunks.Rollouts.Rewards, expectedRewards) {
+		t.Errorf("expected rewards %v but got %v", expectedRewards, chunks.Rollouts.Rewards)
+	}
+	for i, expected := range []int{0, 2, 4} {
+		if actual := chunks.BurnIn(i); actual != expected {
+			t.Errorf("window %d: expected burn-in %d but got %d", i, expected, actual)
+		}
+	}
+	if n := chunks.NumWindowSteps(); n != 5 {
+		t.Errorf("expected 5 window steps but got %d", n)
+	}
+}
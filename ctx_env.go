@@ -0,0 +1,256 @@
+package anyrl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+)
+
+// ErrEnvTimeout is returned when an environment takes too
+// long to reset or step.
+var ErrEnvTimeout = errors.New("environment timed out")
+
+// A CtxEnv is an Env which supports cancellation.
+//
+// If a context is cancelled before the call completes,
+// the environment should return the context's error.
+// After a call is cancelled, the environment should be
+// reset before it is stepped again.
+//
+// The wrappers in this package implement CtxEnv by
+// passing the context to the wrapped Env, so a timeout
+// only leaves work running in the background if the
+// innermost Env is not a CtxEnv.
+type CtxEnv interface {
+	Env
+
+	ResetCtx(ctx context.Context) (observation []float64, err error)
+	StepCtx(ctx context.Context, action []float64) (observation []float64,
+		reward float64, done bool, err error)
+}
+
+// EnvResetCtx resets an Env, stopping early if ctx is
+// done.
+//
+// If e is a CtxEnv, e.ResetCtx is used.
+// Otherwise, e.Reset is run in the background, and it may
+// continue to run after this returns.
+// In that case, e must not be used again after ctx is
+// done, since the abandoned call may still modify it.
+//
+// If ctx can never be done (e.g. context.Background()),
+// e.Reset is called directly.
+//
+// If the context's deadline is exceeded, ErrEnvTimeout
+// is returned.
+func EnvResetCtx(ctx context.Context, e Env) (obs []float64, err error) {
+	if c, ok := e.(CtxEnv); ok {
+		obs, err = c.ResetCtx(ctx)
+		return obs, timeoutErr(err)
+	} else if ctx.Done() == nil {
+		return e.Reset()
+	}
+	type result struct {
+		obs []float64
+		err error
+	}
+	resChan := make(chan result, 1)
+	go func() {
+		var r result
+		r.obs, r.err = e.Reset()
+		resChan <- r
+	}()
+	select {
+	case r := <-resChan:
+		return r.obs, r.err
+	case <-ctx.Done():
+		return nil, timeoutErr(ctx.Err())
+	}
+}
+
+// EnvStepCtx steps an Env, stopping early if ctx is done.
+//
+// See EnvResetCtx for details.
+func EnvStepCtx(ctx context.Context, e Env, action []float64) (obs []float64,
+	reward float64, done bool, err error) {
+	if c, ok := e.(CtxEnv); ok {
+		obs, reward, done, err = c.StepCtx(ctx, action)
+		return obs, reward, done, timeoutErr(err)
+	} else if ctx.Done() == nil {
+		return e.Step(action)
+	}
+	type result struct {
+		obs    []float64
+		reward float64
+		done   bool
+		err    error
+	}
+	resChan := make(chan result, 1)
+	go func() {
+		var r result
+		r.obs, r.reward, r.done, r.err = e.Step(action)
+		resChan <- r
+	}()
+	select {
+	case r := <-resChan:
+		return r.obs, r.reward, r.done, r.err
+	case <-ctx.Done():
+		return nil, 0, false, timeoutErr(ctx.Err())
+	}
+}
+
+// A CtxBatchEnv is a BatchEnv which supports
+// cancellation.
+//
+// The BatchEnv returned by EnvBatch implements
+// CtxBatchEnv.
+type CtxBatchEnv interface {
+	BatchEnv
+
+	ResetCtx(ctx context.Context, c anyvec.Creator) (obs *anyseq.Batch, err error)
+	StepCtx(ctx context.Context, actions *anyseq.Batch) (obs *anyseq.Batch,
+		rewards []float64, finalObs [][]float64, err error)
+}
+
+// BatchResetCtx is like EnvResetCtx, but for a BatchEnv.
+func BatchResetCtx(ctx context.Context, e BatchEnv,
+	c anyvec.Creator) (*anyseq.Batch, error) {
+	if b, ok := e.(CtxBatchEnv); ok {
+		obs, err := b.ResetCtx(ctx, c)
+		return obs, timeoutErr(err)
+	} else if ctx.Done() == nil {
+		return e.Reset(c)
+	}
+	type result struct {
+		obs *anyseq.Batch
+		err error
+	}
+	resChan := make(chan result, 1)
+	go func() {
+		var r result
+		r.obs, r.err = e.Reset(c)
+		resChan <- r
+	}()
+	select {
+	case r := <-resChan:
+		return r.obs, r.err
+	case <-ctx.Done():
+		return nil, timeoutErr(ctx.Err())
+	}
+}
+
+// BatchStepCtx is like EnvStepCtx, but for a BatchEnv.
+func BatchStepCtx(ctx context.Context, e BatchEnv, actions *anyseq.Batch) (*anyseq.Batch,
+	[]float64, [][]float64, error) {
+	if b, ok := e.(CtxBatchEnv); ok {
+		obs, rewards, finalObs, err := b.StepCtx(ctx, actions)
+		return obs, rewards, finalObs, timeoutErr(err)
+	} else if ctx.Done() == nil {
+		return e.Step(actions)
+	}
+	type result struct {
+		obs      *anyseq.Batch
+		rewards  []float64
+		finalObs [][]float64
+		err      error
+	}
+	resChan := make(chan result, 1)
+	go func() {
+		var r result
+		r.obs, r.rewards, r.finalObs, r.err = e.Step(actions)
+		resChan <- r
+	}()
+	select {
+	case r := <-resChan:
+		return r.obs, r.rewards, r.finalObs, r.err
+	case <-ctx.Done():
+		return nil, nil, nil, timeoutErr(ctx.Err())
+	}
+}
+
+func timeoutErr(err error) error {
+	if err == context.DeadlineExceeded {
+		return ErrEnvTimeout
+	}
+	return err
+}
+
+// TimeoutEnv wraps an Env and fails with ErrEnvTimeout if
+// a call to Reset or Step takes longer than Timeout.
+//
+// A timed out call may continue running in the
+// background, so the wrapped Env should not be used again
+// unless it is a CtxEnv.
+// TimeoutEnv enforces this: after a timeout, every call
+// fails with ErrEnvTimeout unless the wrapped Env is a
+// CtxEnv.
+type TimeoutEnv struct {
+	Env
+	Timeout time.Duration
+
+	abandoned bool
+}
+
+// Reset resets the environment.
+func (t *TimeoutEnv) Reset() ([]float64, error) {
+	return t.ResetCtx(context.Background())
+}
+
+// Step takes a step in the environment.
+func (t *TimeoutEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return t.StepCtx(context.Background(), action)
+}
+
+// ResetCtx resets the environment, stopping early if the
+// context is done.
+func (t *TimeoutEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	if t.abandoned {
+		return nil, ErrEnvTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	obs, err := EnvResetCtx(ctx, t.Env)
+	t.checkAbandoned(ctx, err)
+	return obs, err
+}
+
+// StepCtx takes a step in the environment, stopping
+// early if the context is done.
+func (t *TimeoutEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	if t.abandoned {
+		return nil, 0, false, ErrEnvTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	obs, rew, done, err := EnvStepCtx(ctx, t.Env, action)
+	t.checkAbandoned(ctx, err)
+	return obs, rew, done, err
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (t *TimeoutEnv) Truncated() bool {
+	return EnvTruncated(t.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (t *TimeoutEnv) StepInfo() interface{} {
+	return EnvStepInfo(t.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (t *TimeoutEnv) Seed(seed int64) {
+	SeedEnv(t.Env, seed)
+}
+
+func (t *TimeoutEnv) checkAbandoned(ctx context.Context, err error) {
+	if err != nil && ctx.Err() != nil {
+		if _, ok := t.Env.(CtxEnv); !ok {
+			t.abandoned = true
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package anyrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestTimeoutEnv(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	env := &TimeoutEnv{
+		Env:     &hangEnv{Hang: hang, HangStep: 2},
+		Timeout: time.Millisecond * 50,
+	}
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := env.Step([]float64{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := env.Step([]float64{1}); err != ErrEnvTimeout {
+		t.Errorf("expected ErrEnvTimeout but got %v", err)
+	}
+}
+
+func TestTimeoutEnvAbandoned(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	env := &TimeoutEnv{
+		Env:     &hangEnv{Hang: hang, HangStep: 1},
+		Timeout: time.Millisecond * 50,
+	}
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := env.Step([]float64{1}); err != ErrEnvTimeout {
+		t.Errorf("expected ErrEnvTimeout but got %v", err)
+	}
+	if _, err := env.Reset(); err != ErrEnvTimeout {
+		t.Errorf("expected ErrEnvTimeout after abandoned call but got %v", err)
+	}
+}
+
+func TestWrapperCtx(t *testing.T) {
+	monitor, err := NewMonitor(nil, MonitorJSONL, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &ctxHangEnv{HangStep: 2}
+	var env Env = &MaxStepsEnv{
+		Env: &FrameStackEnv{
+			Env: &MonitorEnv{
+				Env:     &ObsNormEnv{Env: inner, Stats: &RunningStats{}},
+				Monitor: monitor,
+			},
+			NumFrames: 2,
+		},
+		MaxSteps: 10,
+	}
+	env = &TimeoutEnv{Env: env, Timeout: time.Millisecond * 50}
+	for i := 0; i < 2; i++ {
+		if _, err := env.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := env.Step([]float64{1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := env.Step([]float64{1}); err != ErrEnvTimeout {
+			t.Errorf("expected ErrEnvTimeout but got %v", err)
+		}
+		if !inner.Cancelled {
+			t.Error("context was not forwarded")
+		}
+		inner.Cancelled = false
+	}
+}
+
+func TestRNNRollerCancel(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	c := anyvec64.DefaultCreator{}
+	roller := &RNNRoller{
+		Block:       anyrnn.NewLSTM(c, 1, 2),
+		ActionSpace: Softmax{},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		cancel()
+	}()
+	_, err := roller.RolloutCtx(ctx, &hangEnv{Hang: hang, HangStep: 3})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// hangEnv is an Env which blocks on a given timestep until
+// the Hang channel is closed.
+type hangEnv struct {
+	Hang     <-chan struct{}
+	HangStep int
+
+	timestep int
+}
+
+func (h *hangEnv) Reset() ([]float64, error) {
+	h.timestep = 0
+	return []float64{0}, nil
+}
+
+func (h *hangEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	h.timestep++
+	if h.timestep == h.HangStep {
+		<-h.Hang
+	}
+	return []float64{float64(h.timestep)}, 0, false, nil
+}
+
+// ctxHangEnv is a CtxEnv which blocks on a given timestep
+// until the context is done.
+type ctxHangEnv struct {
+	HangStep  int
+	Cancelled bool
+
+	timestep int
+}
+
+func (c *ctxHangEnv) Reset() ([]float64, error) {
+	return c.ResetCtx(context.Background())
+}
+
+func (c *ctxHangEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return c.StepCtx(context.Background(), action)
+}
+
+func (c *ctxHangEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	c.timestep = 0
+	return []float64{0}, nil
+}
+
+func (c *ctxHangEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	c.timestep++
+	if c.timestep == c.HangStep {
+		<-ctx.Done()
+		c.Cancelled = true
+		return nil, 0, false, ctx.Err()
+	}
+	return []float64{float64(c.timestep)}, 0, false, nil
+}
@@ -1,8 +1,10 @@
 package anyrl
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/unixpickle/essentials"
 	gym "github.com/unixpickle/gym-socket-api/binding-go"
@@ -19,21 +21,244 @@ type Env interface {
 		reward float64, done bool, err error)
 }
 
+// A Seeder is an Env whose randomness can be seeded for
+// reproducibility.
+type Seeder interface {
+	// Seed resets the environment's random number
+	// generator using the given seed.
+	//
+	// The seed takes effect for all future episodes.
+	Seed(seed int64)
+}
+
+// SeedEnv seeds e if it is a Seeder.
+//
+// It returns false if e is not a Seeder.
+func SeedEnv(e Env, seed int64) bool {
+	if s, ok := e.(Seeder); ok {
+		s.Seed(seed)
+		return true
+	}
+	return false
+}
+
+// A Truncater is an Env which can report whether its
+// most recent episode was cut off by a time limit rather
+// than ending in a true terminal state.
+//
+// Truncated episodes should not be treated as if their
+// final states had zero future value.
+type Truncater interface {
+	// Truncated returns true if the last call to Step
+	// ended the episode because of a time limit.
+	Truncated() bool
+}
+
+// EnvTruncated returns the result of e.Truncated() if e
+// is a Truncater, or false otherwise.
+func EnvTruncated(e Env) bool {
+	if t, ok := e.(Truncater); ok {
+		return t.Truncated()
+	}
+	return false
+}
+
+// A StepInfoer is an Env which exposes extra information
+// about its most recent step.
+//
+// The Env wrappers in this package are StepInfoers which
+// forward the info of the Envs they wrap, so that a
+// GymInfoEnv's info can be read through them.
+type StepInfoer interface {
+	// StepInfo returns the info value produced by the
+	// most recent step, or nil if there is none.
+	StepInfo() interface{}
+}
+
+// EnvStepInfo returns the result of e.StepInfo() if e is
+// a StepInfoer, or nil otherwise.
+func EnvStepInfo(e Env) interface{} {
+	if s, ok := e.(StepInfoer); ok {
+		return s.StepInfo()
+	}
+	return nil
+}
+
+// A GymInfoEnv is an Env backed by OpenAI Gym which
+// exposes extra information about the environment.
+//
+// The Env returned by GymEnv implements GymInfoEnv.
+type GymInfoEnv interface {
+	Env
+
+	// StepInfo returns the info value produced by the
+	// most recent step, as decoded from JSON.
+	// It returns nil if no step has been taken since the
+	// last reset.
+	StepInfo() interface{}
+
+	// ActionSpace describes the action space.
+	ActionSpace() *GymSpace
+
+	// ObsSpace describes the observation space.
+	ObsSpace() *GymSpace
+}
+
+// GymSpace describes an OpenAI Gym space and how it is
+// represented as a vector.
+type GymSpace struct {
+	// Type is the Gym type name, such as "Box".
+	Type string
+
+	// Shape is the shape of Box spaces.
+	Shape []int
+
+	// N is the number of options for Discrete spaces and
+	// the number of values for MultiBinary spaces.
+	N int
+
+	// Low and High are the bounds of Box spaces and the
+	// inclusive ranges of MultiDiscrete spaces.
+	Low  []float64
+	High []float64
+
+	// VecLen is the length of the vectors which represent
+	// elements of the space.
+	VecLen int
+
+	// Subspaces contains the child spaces of composite
+	// spaces, such as Tuple spaces.
+	// For Dict spaces, the subspaces are sorted by key.
+	Subspaces []*GymSpace
+
+	conv gymSpaceConverter
+}
+
+// RoundTrip converts a vector to a Gym object, encodes
+// the object as JSON, and converts the result back to a
+// vector.
+//
+// For a valid vector, the result should equal the input.
+// This is useful for testing the conversion logic.
+func (g *GymSpace) RoundTrip(vec []float64) (res []float64, err error) {
+	defer essentials.AddCtxTo("round trip "+g.Type, &err)
+	obj, err := g.conv.ToGym(vec)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return g.conv.FromGym(rawGymObs(data))
+}
+
+// ActionTuple produces an action space which can be used
+// to select actions in the space.
+//
+// Box spaces use Gaussian, Discrete spaces use Softmax,
+// and MultiBinary spaces use Bernoulli.
+// MultiDiscrete spaces use one Softmax per dimension.
+// Tuple spaces are flattened into a single Tuple.
+// Dict spaces are flattened the same way, with the
+// subspaces in sorted key order, matching the vectors
+// produced for Dict observations.
+//
+// GymEnv does not support Dict action spaces, so the Dict
+// layout is mainly useful for models that predict or
+// reconstruct Dict observations.
+func (g *GymSpace) ActionTuple() (*Tuple, error) {
+	switch g.Type {
+	case "Box":
+		return &Tuple{
+			Spaces:      []interface{}{Gaussian{}},
+			ParamSizes:  []int{g.VecLen * 2},
+			SampleSizes: []int{g.VecLen},
+		}, nil
+	case "Discrete":
+		return &Tuple{
+			Spaces:      []interface{}{Softmax{}},
+			ParamSizes:  []int{g.N},
+			SampleSizes: []int{g.N},
+		}, nil
+	case "MultiBinary":
+		return &Tuple{
+			Spaces:      []interface{}{&Bernoulli{}},
+			ParamSizes:  []int{g.N},
+			SampleSizes: []int{g.N},
+		}, nil
+	case "MultiDiscrete":
+		res := &Tuple{}
+		for _, n := range multiDiscreteSizes(g.Low, g.High) {
+			res.Spaces = append(res.Spaces, Softmax{})
+			res.ParamSizes = append(res.ParamSizes, n)
+			res.SampleSizes = append(res.SampleSizes, n)
+		}
+		return res, nil
+	case "Tuple", "Dict":
+		res := &Tuple{}
+		for _, sub := range g.Subspaces {
+			subTuple, err := sub.ActionTuple()
+			if err != nil {
+				return nil, err
+			}
+			res.Spaces = append(res.Spaces, subTuple.Spaces...)
+			res.ParamSizes = append(res.ParamSizes, subTuple.ParamSizes...)
+			res.SampleSizes = append(res.SampleSizes, subTuple.SampleSizes...)
+		}
+		return res, nil
+	default:
+		return nil, errors.New("no action space for: " + g.Type)
+	}
+}
+
+func newGymSpace(s *gym.Space) (*GymSpace, error) {
+	conv, err := converterForSpace(s)
+	if err != nil {
+		return nil, err
+	}
+	res := &GymSpace{
+		Type:   s.Type,
+		Shape:  s.Shape,
+		N:      s.N,
+		Low:    s.Low,
+		High:   s.High,
+		VecLen: conv.VecLen(),
+		conv:   conv,
+	}
+	for _, subSpace := range s.Subspaces {
+		sub, err := newGymSpace(subSpace)
+		if err != nil {
+			return nil, err
+		}
+		res.Subspaces = append(res.Subspaces, sub)
+	}
+	return res, nil
+}
+
 type gymEnv struct {
-	env    gym.Env
-	render bool
+	env       gym.Env
+	render    bool
+	truncated bool
+	info      interface{}
 
-	actConv gymSpaceConverter
-	obsConv gymSpaceConverter
+	actConv  gymSpaceConverter
+	obsConv  gymSpaceConverter
+	actSpace *GymSpace
+	obsSpace *GymSpace
 }
 
 // GymEnv creates an Env from an OpenAI Gym instance.
 //
 // This will fail if the instance requires an unsupported
 // space type or if it fails to fetch space info.
+// Dict spaces are only supported for observations, since
+// the key names are learned from observations.
 //
 // If render is true, then the environment will be
 // graphically rendered after every step.
+//
+// The resulting Env implements GymInfoEnv and Truncater.
 func GymEnv(e gym.Env, render bool) (env Env, err error) {
 	defer essentials.AddCtxTo("create gym Env", &err)
 	actionSpace, err := e.ActionSpace()
@@ -44,24 +269,31 @@ func GymEnv(e gym.Env, render bool) (env Env, err error) {
 	if err != nil {
 		return nil, err
 	}
-	actConv, err := converterForSpace(actionSpace)
+	actInfo, err := newGymSpace(actionSpace)
 	if err != nil {
 		return nil, err
 	}
-	obsConv, err := converterForSpace(obsSpace)
+	if containsDictSpace(actionSpace) {
+		return nil, errors.New("Dict action spaces are not supported")
+	}
+	obsInfo, err := newGymSpace(obsSpace)
 	if err != nil {
 		return nil, err
 	}
 	return &gymEnv{
-		env:     e,
-		actConv: actConv,
-		obsConv: obsConv,
-		render:  render,
+		env:      e,
+		actConv:  actInfo.conv,
+		obsConv:  obsInfo.conv,
+		actSpace: actInfo,
+		obsSpace: obsInfo,
+		render:   render,
 	}, nil
 }
 
 func (g *gymEnv) Reset() (obsVec []float64, err error) {
 	defer essentials.AddCtxTo("reset gym Env", &err)
+	g.truncated = false
+	g.info = nil
 	obs, err := g.env.Reset()
 	if err != nil {
 		return nil, err
@@ -82,10 +314,13 @@ func (g *gymEnv) Step(action []float64) (obsVec []float64, reward float64,
 		return
 	}
 	var obs gym.Obs
-	obs, reward, done, _, err = g.env.Step(gymAction)
+	var info interface{}
+	obs, reward, done, info, err = g.env.Step(gymAction)
 	if err != nil {
 		return
 	}
+	g.info = info
+	g.truncated = done && gymInfoTruncated(info)
 	if g.render {
 		if err = g.env.Render(); err != nil {
 			return
@@ -95,6 +330,47 @@ func (g *gymEnv) Step(action []float64) (obsVec []float64, reward float64,
 	return
 }
 
+// Truncated returns true if Gym's TimeLimit wrapper ended
+// the last episode.
+func (g *gymEnv) Truncated() bool {
+	return g.truncated
+}
+
+// StepInfo returns the info from the last step.
+func (g *gymEnv) StepInfo() interface{} {
+	return g.info
+}
+
+// ActionSpace describes the action space.
+func (g *gymEnv) ActionSpace() *GymSpace {
+	return g.actSpace
+}
+
+// ObsSpace describes the observation space.
+func (g *gymEnv) ObsSpace() *GymSpace {
+	return g.obsSpace
+}
+
+func containsDictSpace(s *gym.Space) bool {
+	if s.Type == "Dict" {
+		return true
+	}
+	for _, sub := range s.Subspaces {
+		if containsDictSpace(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func gymInfoTruncated(info interface{}) bool {
+	if m, ok := info.(map[string]interface{}); ok {
+		truncated, _ := m["TimeLimit.truncated"].(bool)
+		return truncated
+	}
+	return false
+}
+
 type gymSpaceConverter interface {
 	VecLen() int
 	ToGym(in []float64) (interface{}, error)
@@ -113,7 +389,7 @@ func converterForSpace(s *gym.Space) (gymSpaceConverter, error) {
 		return &discreteSpaceConverter{N: s.N}, nil
 	case "MultiBinary":
 		return &multiBinarySpaceConverter{N: s.N}, nil
-	case "Tuple":
+	case "Tuple", "Dict":
 		var subConvs []gymSpaceConverter
 		for _, subSpace := range s.Subspaces {
 			subConv, err := converterForSpace(subSpace)
@@ -122,7 +398,20 @@ func converterForSpace(s *gym.Space) (gymSpaceConverter, error) {
 			}
 			subConvs = append(subConvs, subConv)
 		}
+		if s.Type == "Dict" {
+			return &dictSpaceConverter{Spaces: subConvs}, nil
+		}
 		return &tupleSpaceConverter{Spaces: subConvs}, nil
+	case "MultiDiscrete":
+		if len(s.Low) != len(s.High) {
+			return nil, errors.New("mismatching MultiDiscrete bounds")
+		}
+		for i, low := range s.Low {
+			if s.High[i] < low {
+				return nil, errors.New("empty MultiDiscrete range")
+			}
+		}
+		return &multiDiscreteSpaceConverter{Low: s.Low, High: s.High}, nil
 	default:
 		return nil, errors.New("unsupported space: " + s.Type)
 	}
@@ -253,3 +542,126 @@ func (t *tupleSpaceConverter) FromGym(in gym.Obs) ([]float64, error) {
 	}
 	return reses, nil
 }
+
+type multiDiscreteSpaceConverter struct {
+	Low  []float64
+	High []float64
+}
+
+func (m *multiDiscreteSpaceConverter) VecLen() int {
+	var size int
+	for _, n := range multiDiscreteSizes(m.Low, m.High) {
+		size += n
+	}
+	return size
+}
+
+func (m *multiDiscreteSpaceConverter) ToGym(in []float64) (interface{}, error) {
+	if len(in) != m.VecLen() {
+		return nil, errSpaceLength
+	}
+	res := make([]int, len(m.Low))
+	for i, n := range multiDiscreteSizes(m.Low, m.High) {
+		subConv := discreteSpaceConverter{N: n}
+		idx, err := subConv.ToGym(in[:n])
+		if err != nil {
+			return nil, err
+		}
+		in = in[n:]
+		res[i] = int(m.Low[i]) + idx.(int)
+	}
+	return res, nil
+}
+
+func (m *multiDiscreteSpaceConverter) FromGym(in gym.Obs) ([]float64, error) {
+	var nums []int
+	if err := in.Unmarshal(&nums); err != nil {
+		return nil, err
+	}
+	if len(nums) != len(m.Low) {
+		return nil, fmt.Errorf("expected %d MultiDiscrete values but got %d",
+			len(m.Low), len(nums))
+	}
+	var res []float64
+	for i, n := range multiDiscreteSizes(m.Low, m.High) {
+		idx := nums[i] - int(m.Low[i])
+		if idx < 0 || idx >= n {
+			return nil, fmt.Errorf("MultiDiscrete value out of range: %d", nums[i])
+		}
+		oneHot := make([]float64, n)
+		oneHot[idx] = 1
+		res = append(res, oneHot...)
+	}
+	return res, nil
+}
+
+func multiDiscreteSizes(low, high []float64) []int {
+	res := make([]int, len(low))
+	for i, l := range low {
+		res[i] = int(high[i]-l) + 1
+	}
+	return res
+}
+
+// dictSpaceConverter converts Dict spaces by flattening
+// the values in order of their sorted keys.
+type dictSpaceConverter struct {
+	Spaces []gymSpaceConverter
+
+	// Keys is learned from the first observation, since
+	// space descriptions do not include key names.
+	Keys []string
+}
+
+func (d *dictSpaceConverter) VecLen() int {
+	return (&tupleSpaceConverter{Spaces: d.Spaces}).VecLen()
+}
+
+func (d *dictSpaceConverter) ToGym(in []float64) (interface{}, error) {
+	if d.Keys == nil {
+		return nil, errors.New("Dict keys are unknown")
+	}
+	values, err := (&tupleSpaceConverter{Spaces: d.Spaces}).ToGym(in)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	for i, value := range values.([]interface{}) {
+		res[d.Keys[i]] = value
+	}
+	return res, nil
+}
+
+func (d *dictSpaceConverter) FromGym(in gym.Obs) ([]float64, error) {
+	var rawValues map[string]json.RawMessage
+	if err := in.Unmarshal(&rawValues); err != nil {
+		return nil, err
+	}
+	if len(rawValues) != len(d.Spaces) {
+		return nil, fmt.Errorf("expected %d dict entries but got %d", len(d.Spaces),
+			len(rawValues))
+	}
+	var keys []string
+	for key := range rawValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	d.Keys = keys
+
+	var reses []float64
+	for i, key := range keys {
+		subVec, err := d.Spaces[i].FromGym(rawGymObs(rawValues[key]))
+		if err != nil {
+			return nil, err
+		}
+		reses = append(reses, subVec...)
+	}
+	return reses, nil
+}
+
+// rawGymObs is a gym.Obs backed by raw JSON.
+type rawGymObs json.RawMessage
+
+func (r rawGymObs) Unmarshal(dst interface{}) error {
+	return json.Unmarshal(r, dst)
+}
@@ -0,0 +1,139 @@
+package anyrl
+
+import (
+	"reflect"
+	"testing"
+
+	gym "github.com/unixpickle/gym-socket-api/binding-go"
+)
+
+func TestGymSpaceActionTuple(t *testing.T) {
+	space, err := newGymSpace(&gym.Space{
+		Type: "Tuple",
+		Subspaces: []*gym.Space{
+			{Type: "Discrete", N: 3},
+			{Type: "Box", Shape: []int{2, 2}},
+			{Type: "MultiBinary", N: 4},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if space.VecLen != 3+4+4 {
+		t.Errorf("unexpected vector length: %d", space.VecLen)
+	}
+	if len(space.Subspaces) != 3 || space.Subspaces[1].VecLen != 4 {
+		t.Errorf("unexpected subspaces: %v", space.Subspaces)
+	}
+
+	actual, err := space.ActionTuple()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Tuple{
+		Spaces:      []interface{}{Softmax{}, Gaussian{}, &Bernoulli{}},
+		ParamSizes:  []int{3, 8, 4},
+		SampleSizes: []int{3, 4, 4},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+}
+
+func TestGymSpaceMultiDiscreteDict(t *testing.T) {
+	subspaces := []*gym.Space{
+		{Type: "MultiDiscrete", Low: []float64{0, 1}, High: []float64{2, 2}},
+		{Type: "Discrete", N: 2},
+	}
+	space, err := newGymSpace(&gym.Space{Type: "Dict", Subspaces: subspaces})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if space.VecLen != 3+2+2 {
+		t.Errorf("unexpected vector length: %d", space.VecLen)
+	}
+	expected := &Tuple{
+		Spaces:      []interface{}{Softmax{}, Softmax{}, Softmax{}},
+		ParamSizes:  []int{3, 2, 2},
+		SampleSizes: []int{3, 2, 2},
+	}
+	actual, err := space.ActionTuple()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Dict: expected %v but got %v", expected, actual)
+	}
+
+	space, err = newGymSpace(&gym.Space{Type: "Tuple", Subspaces: subspaces})
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err = space.ActionTuple()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Tuple: expected %v but got %v", expected, actual)
+	}
+}
+
+func TestMultiDiscreteSpaceConverter(t *testing.T) {
+	conv := &multiDiscreteSpaceConverter{
+		Low:  []float64{0, 1},
+		High: []float64{2, 2},
+	}
+	vec, err := conv.FromGym(rawGymObs(`[2, 1]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float64{0, 0, 1, 1, 0}; !reflect.DeepEqual(vec, expected) {
+		t.Errorf("expected %v but got %v", expected, vec)
+	}
+	action, err := conv.ToGym(vec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int{2, 1}; !reflect.DeepEqual(action, expected) {
+		t.Errorf("expected %v but got %v", expected, action)
+	}
+}
+
+func TestDictSpaceConverter(t *testing.T) {
+	conv := &dictSpaceConverter{
+		Spaces: []gymSpaceConverter{
+			&discreteSpaceConverter{N: 2},
+			&discreteSpaceConverter{N: 3},
+		},
+	}
+	vec, err := conv.FromGym(rawGymObs(`{"b": 2, "a": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float64{0, 1, 0, 0, 1}; !reflect.DeepEqual(vec, expected) {
+		t.Errorf("expected %v but got %v", expected, vec)
+	}
+	action, err := conv.ToGym(vec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"a": 1, "b": 2}
+	if !reflect.DeepEqual(action, expected) {
+		t.Errorf("expected %v but got %v", expected, action)
+	}
+}
+
+func TestDictSpaceConverterUnknownKeys(t *testing.T) {
+	conv, err := converterForSpace(&gym.Space{
+		Type: "Dict",
+		Subspaces: []*gym.Space{
+			{Type: "Discrete", N: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conv.ToGym([]float64{1, 0}); err == nil {
+		t.Error("expected error before keys are known")
+	}
+}
@@ -43,27 +43,25 @@ func main() {
 	env, err := anyrl.GymEnv(client, RenderEnv)
 	must(err)
 
+	// Actions are drawn from the tuple space described by
+	// Tuple(Discrete(2), Discrete(2), Discrete(5)).
+	spaceInfo := env.(anyrl.GymInfoEnv)
+	actionSpace, err := spaceInfo.ActionSpace().ActionTuple()
+	must(err)
+	var paramSize int
+	for _, size := range actionSpace.ParamSizes {
+		paramSize += size
+	}
+
 	// Create a neural network policy.
 	policy := &anyrnn.LayerBlock{
 		Layer: anynet.Net{
-			anynet.NewFC(creator, 6, 32),
+			anynet.NewFC(creator, spaceInfo.ObsSpace().VecLen, 32),
 			anynet.Tanh,
 
 			// Zero last layer encourages exploration.
-			anynet.NewFCZero(creator, 32, 2+5),
-		},
-	}
-
-	// Actions are drawn from the tuple space described by
-	// Tuple(Discrete(2), Discrete(2), Discrete(5)).
-	actionSpace := &anyrl.Tuple{
-		Spaces: []interface{}{
-			&anyrl.Bernoulli{OneHot: true},
-			&anyrl.Bernoulli{OneHot: true},
-			anyrl.Softmax{},
+			anynet.NewFCZero(creator, 32, paramSize),
 		},
-		ParamSizes:  []int{1, 1, 5},
-		SampleSizes: []int{2, 2, 5},
 	}
 
 	// Setup Trust Region Policy Optimization for training.
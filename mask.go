@@ -0,0 +1,264 @@
+package anyrl
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anynet"
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+)
+
+// maskPenalty is subtracted from the logits of invalid
+// actions.
+const maskPenalty = 1e10
+
+func init() {
+	var m MaskBlock
+	serializer.RegisterTypedDeserializer(m.SerializerType(), DeserializeMaskBlock)
+}
+
+// An ActionMasker is an Env with state-dependent invalid
+// actions.
+type ActionMasker interface {
+	// ActionMask returns a vector with a 1 for every valid
+	// action and a 0 for every invalid action.
+	//
+	// The mask applies to the next call to Step.
+	ActionMask() []float64
+}
+
+// MaskEnv wraps an ActionMasker and appends the action
+// mask to every observation.
+//
+// This way, the mask is recorded with the rest of the
+// observation, and MaskBlock can forward it to a
+// MaskedSoftmax.
+type MaskEnv struct {
+	Env
+}
+
+// Reset resets the environment.
+func (m *MaskEnv) Reset() ([]float64, error) {
+	return m.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (m *MaskEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	obs, err := EnvResetCtx(ctx, m.Env)
+	if err != nil {
+		return nil, err
+	}
+	return m.appendMask(obs)
+}
+
+// Step takes a step in the environment.
+func (m *MaskEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return m.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (m *MaskEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	obs, rew, done, err := EnvStepCtx(ctx, m.Env, action)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	obs, err = m.appendMask(obs)
+	return obs, rew, done, err
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (m *MaskEnv) Truncated() bool {
+	return EnvTruncated(m.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (m *MaskEnv) StepInfo() interface{} {
+	return EnvStepInfo(m.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (m *MaskEnv) Seed(seed int64) {
+	SeedEnv(m.Env, seed)
+}
+
+func (m *MaskEnv) appendMask(obs []float64) ([]float64, error) {
+	masker, ok := m.Env.(ActionMasker)
+	if !ok {
+		return nil, errors.New("environment is not an ActionMasker")
+	}
+	return append(append([]float64{}, obs...), masker.ActionMask()...), nil
+}
+
+// MaskedSoftmax is like Softmax, but with a validity mask
+// for the actions.
+//
+// Each parameter vector consists of logits followed by a
+// mask of the same length, where a 1 indicates a valid
+// action and a 0 indicates an invalid action.
+// Invalid actions are given (nearly) zero probability.
+//
+// Samples are one-hot vectors with one component per
+// logit, so the parameter vectors are twice as long as
+// the samples.
+//
+// Use MaskBlock to produce parameter vectors from a
+// policy's logits and the masks from a MaskEnv.
+type MaskedSoftmax struct {
+	// Rand is used for sampling.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+}
+
+// Sample samples one-hot vectors from the masked
+// distribution.
+func (m MaskedSoftmax) Sample(params anyvec.Vector, batch int) anyvec.Vector {
+	logits := m.maskedLogits(anydiff.NewConst(params), batch).Output()
+	return Softmax{Rand: m.Rand}.Sample(logits, batch)
+}
+
+// Mode produces one-hot vectors for the most likely
+// valid actions.
+func (m MaskedSoftmax) Mode(params anyvec.Vector, batch int) anyvec.Vector {
+	logits := m.maskedLogits(anydiff.NewConst(params), batch).Output()
+	return Softmax{}.Mode(logits, batch)
+}
+
+// LogProb computes the output log probabilities.
+func (m MaskedSoftmax) LogProb(params anydiff.Res, output anyvec.Vector,
+	batch int) anydiff.Res {
+	return Softmax{}.LogProb(m.maskedLogits(params, batch), output, batch)
+}
+
+// KL computes the KL divergences between two batches of
+// masked distributions.
+//
+// Both batches should use the same masks.
+func (m MaskedSoftmax) KL(params1, params2 anydiff.Res, batch int) anydiff.Res {
+	return Softmax{}.KL(m.maskedLogits(params1, batch), m.maskedLogits(params2, batch),
+		batch)
+}
+
+// Entropy computes the entropy of the distributions.
+func (m MaskedSoftmax) Entropy(params anydiff.Res, batch int) anydiff.Res {
+	return Softmax{}.Entropy(m.maskedLogits(params, batch), batch)
+}
+
+// maskedLogits extracts the logits from the parameters
+// and applies the masks to them.
+//
+// No gradients flow through the masks.
+func (m MaskedSoftmax) maskedLogits(params anydiff.Res, batch int) anydiff.Res {
+	if params.Output().Len()%(2*batch) != 0 {
+		panic("batch size must divide parameter count")
+	}
+	size := params.Output().Len() / (2 * batch)
+	return anydiff.Pool(params, func(params anydiff.Res) anydiff.Res {
+		parts := unpackTuples(params, []int{size, size}, batch)
+		c := params.Output().Creator()
+		penalty := parts[1].Output().Copy()
+		penalty.AddScalar(c.MakeNumeric(-1))
+		penalty.Scale(c.MakeNumeric(maskPenalty))
+		return anydiff.Add(parts[0], anydiff.NewConst(penalty))
+	})
+}
+
+// MaskBlock wraps an anyrnn.Block whose inputs end with
+// an action mask, such as the observations from a
+// MaskEnv.
+//
+// The mask is removed from each input before it is fed
+// to the wrapped Block, and then it is appended to the
+// corresponding output.
+// Thus, if the wrapped Block produces logits, MaskBlock
+// produces parameters for a MaskedSoftmax.
+//
+// Using a MaskBlock as an RNNRoller's Block feeds the
+// masks to the roller's ActionSpace.
+type MaskBlock struct {
+	Block    anyrnn.Block
+	MaskSize int
+}
+
+// DeserializeMaskBlock deserializes a MaskBlock.
+func DeserializeMaskBlock(d []byte) (res *MaskBlock, err error) {
+	defer essentials.AddCtxTo("deserialize MaskBlock", &err)
+	res = &MaskBlock{}
+	if err := serializer.DeserializeAny(d, &res.Block, &res.MaskSize); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Start produces a start state.
+func (m *MaskBlock) Start(n int) anyrnn.State {
+	return m.Block.Start(n)
+}
+
+// PropagateStart propagates through the start state.
+func (m *MaskBlock) PropagateStart(s anyrnn.StateGrad, g anydiff.Grad) {
+	m.Block.PropagateStart(s, g)
+}
+
+// Step applies the block for a single timestep.
+func (m *MaskBlock) Step(s anyrnn.State, in anyvec.Vector) anyrnn.Res {
+	n := s.Present().NumPresent()
+	inSize := in.Len()/n - m.MaskSize
+	parts := unpackTuples(anydiff.NewConst(in), []int{inSize, m.MaskSize}, n)
+	res := m.Block.Step(s, parts[0].Output())
+	return &maskBlockRes{
+		Res:  res,
+		Mask: parts[1].Output(),
+		N:    n,
+		Out:  packTuples([]anyvec.Vector{res.Output(), parts[1].Output()}, n),
+	}
+}
+
+// Parameters returns the wrapped Block's parameters.
+func (m *MaskBlock) Parameters() []*anydiff.Var {
+	return anynet.AllParameters(m.Block)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a MaskBlock with the serializer package.
+func (m *MaskBlock) SerializerType() string {
+	return "github.com/unixpickle/anyrl.MaskBlock"
+}
+
+// Serialize serializes the block.
+func (m *MaskBlock) Serialize() ([]byte, error) {
+	block, ok := m.Block.(serializer.Serializer)
+	if !ok {
+		return nil, errors.New("serialize MaskBlock: block is not a Serializer")
+	}
+	return serializer.SerializeAny(block, m.MaskSize)
+}
+
+type maskBlockRes struct {
+	anyrnn.Res
+
+	Mask anyvec.Vector
+	N    int
+	Out  anyvec.Vector
+}
+
+func (m *maskBlockRes) Output() anyvec.Vector {
+	return m.Out
+}
+
+func (m *maskBlockRes) Propagate(u anyvec.Vector, s anyrnn.StateGrad,
+	g anydiff.Grad) (anyvec.Vector, anyrnn.StateGrad) {
+	outSize := m.Res.Output().Len() / m.N
+	parts := unpackTuples(anydiff.NewConst(u), []int{outSize, m.Mask.Len() / m.N}, m.N)
+	inGrad, stateGrad := m.Res.Propagate(parts[0].Output(), s, g)
+	maskGrad := m.Mask.Creator().MakeVector(m.Mask.Len())
+	return packTuples([]anyvec.Vector{inGrad, maskGrad}, m.N), stateGrad
+}
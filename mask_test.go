@@ -0,0 +1,128 @@
+package anyrl
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/anydiff"
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestMaskedSoftmaxSample(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	params := c.MakeVectorData([]float64{
+		0.5, 3, -1, 1, 0, 1,
+		2, -0.5, 0.3, 0, 1, 1,
+	})
+	counts := c.MakeVector(6)
+	for i := 0; i < 1000; i++ {
+		counts.Add(MaskedSoftmax{}.Sample(params, 2))
+	}
+	data := counts.Data().([]float64)
+	if data[1] != 0 || data[3] != 0 {
+		t.Errorf("sampled masked action: %v", data)
+	}
+	if data[0]+data[2] != 1000 || data[4]+data[5] != 1000 {
+		t.Errorf("bad sample counts: %v", data)
+	}
+}
+
+func TestMaskedSoftmaxLogProb(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	params := c.MakeVectorData([]float64{
+		0.5, 3, -1, 1, 0, 1,
+		2, -0.5, 0.3, 1, 1, 1,
+	})
+	sampled := c.MakeVectorData([]float64{0, 0, 1, 0, 1, 0})
+	actual := MaskedSoftmax{}.LogProb(anydiff.NewConst(params), sampled, 2).Output()
+
+	first := -1 - math.Log(math.Exp(0.5)+math.Exp(-1))
+	second := -0.5 - math.Log(math.Exp(2)+math.Exp(-0.5)+math.Exp(0.3))
+	expected := c.MakeVectorData([]float64{first, second})
+
+	assertSimilar(t, actual, expected)
+}
+
+func TestMaskedSoftmaxEntropy(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	params := c.MakeVectorData([]float64{
+		0.5, 3, -1, 1, 0, 1,
+	})
+	actual := MaskedSoftmax{}.Entropy(anydiff.NewConst(params), 1).Output()
+	expected := Softmax{}.Entropy(anydiff.NewConst(c.MakeVectorData([]float64{
+		0.5, -1,
+	})), 1).Output()
+	assertSimilar(t, actual, expected)
+}
+
+func TestMaskBlock(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	lstm := anyrnn.NewLSTM(c, 2, 3)
+	block := &MaskBlock{Block: lstm, MaskSize: 3}
+
+	in := c.MakeVectorData([]float64{
+		0.5, -1, 1, 0, 1,
+		2, 0.3, 0, 1, 1,
+	})
+	res := block.Step(block.Start(2), in)
+	expectedLogits := lstm.Step(lstm.Start(2), c.MakeVectorData([]float64{
+		0.5, -1, 2, 0.3,
+	})).Output()
+	expected := packTuples([]anyvec.Vector{
+		expectedLogits,
+		c.MakeVectorData([]float64{1, 0, 1, 0, 1, 1}),
+	}, 2)
+	assertSimilar(t, res.Output(), expected)
+
+	upstream := c.MakeVector(12)
+	anyvec.Rand(upstream, anyvec.Normal, nil)
+	inGrad, _ := res.Propagate(upstream, nil, anydiff.Grad{})
+	data := inGrad.Data().([]float64)
+	for _, i := range []int{2, 3, 4, 7, 8, 9} {
+		if data[i] != 0 {
+			t.Errorf("expected zero mask gradient but got %v", data)
+			break
+		}
+	}
+}
+
+func TestMaskEnv(t *testing.T) {
+	env := &MaskEnv{Env: &maskTestEnv{}}
+	obs, err := env.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) != 3 || obs[0] != 0 || obs[1] != 1 || obs[2] != 0 {
+		t.Errorf("unexpected observation: %v", obs)
+	}
+	obs, _, _, err = env.Step([]float64{1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) != 3 || obs[0] != 1 || obs[1] != 0 || obs[2] != 1 {
+		t.Errorf("unexpected observation: %v", obs)
+	}
+}
+
+type maskTestEnv struct {
+	timestep int
+}
+
+func (m *maskTestEnv) Reset() ([]float64, error) {
+	m.timestep = 0
+	return []float64{0}, nil
+}
+
+func (m *maskTestEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	m.timestep++
+	return []float64{float64(m.timestep)}, 0, false, nil
+}
+
+func (m *maskTestEnv) ActionMask() []float64 {
+	if m.timestep%2 == 0 {
+		return []float64{1, 0}
+	}
+	return []float64{0, 1}
+}
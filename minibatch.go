@@ -0,0 +1,105 @@
+package anyrl
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/lazyseq"
+)
+
+// A Minibatch is a subset of the episodes in a
+// RolloutSet.
+type Minibatch struct {
+	// Rollouts contains the selected episodes.
+	// Like with FracReducer, the other episodes are left
+	// out of the tapes but still take up indices.
+	Rollouts *RolloutSet
+
+	// Advantages is the reduced advantage tape, or nil if
+	// no advantages were provided.
+	Advantages lazyseq.Tape
+}
+
+// MinibatchIter splits a RolloutSet into random
+// minibatches of episodes, which is useful for algorithms
+// like PPO that make several passes over the same data.
+type MinibatchIter struct {
+	Rollouts *RolloutSet
+
+	// Advantages, if non-nil, is a tape of per-timestep
+	// values (e.g. from anypg.PPO.Advantage) which is
+	// reduced along with the rollouts.
+	Advantages lazyseq.Tape
+
+	// BatchSize is the number of episodes per minibatch.
+	// The last minibatch in an epoch may be smaller.
+	BatchSize int
+
+	// Rand is used to shuffle the episodes.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	// These TapeMakers are called to produce caches of
+	// the reduced tapes.
+	// If a TapeMaker is nil, no cache is used for the
+	// corresponding tape.
+	//
+	// See FracReducer for more details.
+	MakeInputTape     TapeMaker
+	MakeActionTape    TapeMaker
+	MakeAgentOutTape  TapeMaker
+	MakeAdvantageTape TapeMaker
+
+	perm []int
+}
+
+// Next produces the next minibatch.
+//
+// The episodes are reshuffled at the start of every
+// epoch, so that each episode appears once per epoch.
+func (m *MinibatchIter) Next() *Minibatch {
+	if len(m.perm) == 0 {
+		m.perm = m.shuffle()
+	}
+	size := m.BatchSize
+	if size > len(m.perm) || size <= 0 {
+		size = len(m.perm)
+	}
+	indices := m.perm[:size]
+	m.perm = m.perm[size:]
+	return m.minibatch(indices)
+}
+
+// Epoch produces a full epoch of minibatches, covering
+// every episode exactly once.
+//
+// The next call to Next will start a new epoch.
+func (m *MinibatchIter) Epoch() []*Minibatch {
+	m.perm = m.shuffle()
+	var res []*Minibatch
+	for len(m.perm) > 0 {
+		res = append(res, m.Next())
+	}
+	return res
+}
+
+func (m *MinibatchIter) shuffle() []int {
+	if m.Rand == nil {
+		return rand.Perm(len(m.Rollouts.Rewards))
+	}
+	return m.Rand.Perm(len(m.Rollouts.Rewards))
+}
+
+func (m *MinibatchIter) minibatch(indices []int) *Minibatch {
+	present := make([]bool, len(m.Rollouts.Rewards))
+	for _, i := range indices {
+		present[i] = true
+	}
+	res := &Minibatch{
+		Rollouts: reduceRollouts(m.Rollouts, present, m.MakeInputTape,
+			m.MakeActionTape, m.MakeAgentOutTape),
+	}
+	if m.Advantages != nil {
+		res.Advantages = reduceTape(m.MakeAdvantageTape, m.Advantages, present)
+	}
+	return res
+}
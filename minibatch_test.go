@@ -0,0 +1,66 @@
+package anyrl
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestMinibatchIter(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	var trajs []*Trajectory
+	var advantages [][][]float64
+	for i := 0; i < 5; i++ {
+		traj := &Trajectory{Done: true}
+		var adv [][]float64
+		for j := 0; j <= i; j++ {
+			traj.Observations = append(traj.Observations, []float64{float64(i)})
+			traj.Actions = append(traj.Actions, []float64{1})
+			traj.Rewards = append(traj.Rewards, float64(i))
+			adv = append(adv, []float64{float64(i)})
+		}
+		trajs = append(trajs, traj)
+		advantages = append(advantages, adv)
+	}
+	iter := &MinibatchIter{
+		Rollouts:          TrajectoryRolloutSet(c, trajs),
+		Advantages:        vectorSeqTape(c, advantages),
+		BatchSize:         2,
+		Rand:              rand.New(rand.NewSource(1337)),
+		MakeAdvantageTape: CompressedTapeMaker(Float64Precision),
+	}
+
+	for epoch := 0; epoch < 2; epoch++ {
+		batches := iter.Epoch()
+		if len(batches) != 3 {
+			t.Fatalf("expected 3 minibatches but got %d", len(batches))
+		}
+		seen := map[int]bool{}
+		for _, batch := range batches {
+			var present []bool
+			for i, rews := range batch.Rollouts.Rewards {
+				present = append(present, rews != nil)
+				if rews != nil {
+					if seen[i] {
+						t.Errorf("episode %d appeared twice", i)
+					}
+					seen[i] = true
+				}
+			}
+			for inputs := range batch.Rollouts.Inputs.ReadTape(0, -1) {
+				advs := <-batch.Advantages.ReadTape(0, 1)
+				if !reflect.DeepEqual(advs.Present, present) {
+					t.Errorf("advantage present map %v should match %v", advs.Present,
+						present)
+				}
+				assertSimilar(t, inputs.Packed, advs.Packed)
+				break
+			}
+		}
+		if len(seen) != 5 {
+			t.Errorf("expected 5 episodes but saw %d", len(seen))
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package anyrl
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/essentials"
+)
+
+// MonitorFormat is a file format for episode logs.
+type MonitorFormat int
+
+const (
+	// MonitorCSV produces a CSV file with a header row.
+	MonitorCSV MonitorFormat = iota
+
+	// MonitorJSONL produces one JSON object per line.
+	MonitorJSONL
+)
+
+// EpisodeStats summarizes a completed episode.
+type EpisodeStats struct {
+	// Reward is the total reward.
+	Reward float64 `json:"reward"`
+
+	// Length is the number of timesteps.
+	Length int `json:"length"`
+
+	// Time is the number of seconds between the creation
+	// of the Monitor and the end of the episode.
+	Time float64 `json:"time"`
+
+	// Info contains the requested info fields from the
+	// final step of the episode.
+	Info map[string]interface{} `json:"info,omitempty"`
+}
+
+// A Monitor records statistics about the episodes of one
+// or more environments.
+//
+// It is safe to use a Monitor from multiple Goroutines,
+// so it can be shared by all the environments in a
+// training loop.
+type Monitor struct {
+	lock sync.Mutex
+
+	writer     io.Writer
+	csvWriter  *csv.Writer
+	format     MonitorFormat
+	infoKeys   []string
+	windowSize int
+	start      time.Time
+
+	window     []*EpisodeStats
+	numEpisode int
+}
+
+// NewMonitor creates a Monitor which writes episode
+// statistics to w.
+//
+// If w is nil, the statistics are only kept in memory.
+//
+// The windowSize argument specifies how many recent
+// episodes are stored in memory.
+//
+// The infoKeys are keys to extract from the step info of
+// the environments at the end of each episode (see
+// StepInfoer).
+// Missing keys are left out of the logs.
+// Empty or duplicate keys, and keys which conflict with
+// the reward, length, and time columns, are rejected,
+// since they could never be logged separately.
+func NewMonitor(w io.Writer, format MonitorFormat, windowSize int,
+	infoKeys ...string) (*Monitor, error) {
+	seen := map[string]bool{"reward": true, "length": true, "time": true}
+	for _, key := range infoKeys {
+		if key == "" {
+			return nil, essentials.AddCtx("create monitor", errors.New("empty info key"))
+		} else if seen[key] {
+			return nil, essentials.AddCtx("create monitor",
+				fmt.Errorf("invalid info key: %s", key))
+		}
+		seen[key] = true
+	}
+	m := &Monitor{
+		writer:     w,
+		format:     format,
+		infoKeys:   infoKeys,
+		windowSize: windowSize,
+		start:      time.Now(),
+	}
+	if w != nil && format == MonitorCSV {
+		m.csvWriter = csv.NewWriter(w)
+		header := append([]string{"reward", "length", "time"}, infoKeys...)
+		if err := m.csvWriter.Write(header); err != nil {
+			return nil, essentials.AddCtx("create monitor", err)
+		}
+		m.csvWriter.Flush()
+		if err := m.csvWriter.Error(); err != nil {
+			return nil, essentials.AddCtx("create monitor", err)
+		}
+	}
+	return m, nil
+}
+
+// Wrap creates an Env which reports its episodes to m.
+func (m *Monitor) Wrap(e Env) *MonitorEnv {
+	return &MonitorEnv{Env: e, Monitor: m}
+}
+
+// Recent returns the statistics for the most recent
+// episodes, from oldest to newest.
+func (m *Monitor) Recent() []*EpisodeStats {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]*EpisodeStats{}, m.window...)
+}
+
+// NumEpisodes returns the total number of episodes which
+// have been recorded.
+func (m *Monitor) NumEpisodes() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.numEpisode
+}
+
+// MeanReward returns the mean reward of the episodes in
+// the recent window.
+//
+// It returns 0 if there are no episodes.
+func (m *Monitor) MeanReward() float64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if len(m.window) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, ep := range m.window {
+		sum += ep.Reward
+	}
+	return sum / float64(len(m.window))
+}
+
+// Record records the statistics for an episode.
+//
+// The Time field is filled in automatically.
+func (m *Monitor) Record(stats *EpisodeStats) (err error) {
+	defer essentials.AddCtxTo("record episode", &err)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	stats.Time = time.Since(m.start).Seconds()
+	m.numEpisode++
+	m.window = append(m.window, stats)
+	if len(m.window) > m.windowSize {
+		m.window = m.window[1:]
+	}
+
+	if m.writer == nil {
+		return nil
+	}
+	switch m.format {
+	case MonitorCSV:
+		row := []string{
+			strconv.FormatFloat(stats.Reward, 'g', -1, 64),
+			strconv.Itoa(stats.Length),
+			strconv.FormatFloat(stats.Time, 'f', 6, 64),
+		}
+		for _, key := range m.infoKeys {
+			if val, ok := stats.Info[key]; ok {
+				row = append(row, fmt.Sprint(val))
+			} else {
+				row = append(row, "")
+			}
+		}
+		if err := m.csvWriter.Write(row); err != nil {
+			return err
+		}
+		m.csvWriter.Flush()
+		return m.csvWriter.Error()
+	case MonitorJSONL:
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return err
+		}
+		_, err = m.writer.Write(append(data, '\n'))
+		return err
+	default:
+		return fmt.Errorf("unknown format: %d", m.format)
+	}
+}
+
+// MonitorEnv wraps an Env and reports every completed
+// episode to a Monitor.
+type MonitorEnv struct {
+	Env
+	Monitor *Monitor
+
+	reward float64
+	length int
+}
+
+// Reset resets the environment.
+func (m *MonitorEnv) Reset() ([]float64, error) {
+	return m.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (m *MonitorEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	m.reward = 0
+	m.length = 0
+	return EnvResetCtx(ctx, m.Env)
+}
+
+// Step takes a step in the environment.
+//
+// If the episode ends, its statistics are recorded and
+// any recording errors are returned.
+func (m *MonitorEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return m.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (m *MonitorEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	obs, rew, done, err := EnvStepCtx(ctx, m.Env, action)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	m.reward += rew
+	m.length++
+	if done {
+		stats := &EpisodeStats{
+			Reward: m.reward,
+			Length: m.length,
+			Info:   m.info(),
+		}
+		if err := m.Monitor.Record(stats); err != nil {
+			return nil, 0, false, err
+		}
+	}
+	return obs, rew, done, nil
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (m *MonitorEnv) Truncated() bool {
+	return EnvTruncated(m.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (m *MonitorEnv) StepInfo() interface{} {
+	return EnvStepInfo(m.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (m *MonitorEnv) Seed(seed int64) {
+	SeedEnv(m.Env, seed)
+}
+
+func (m *MonitorEnv) info() map[string]interface{} {
+	if len(m.Monitor.infoKeys) == 0 {
+		return nil
+	}
+	info, ok := EnvStepInfo(m.Env).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	res := map[string]interface{}{}
+	for _, key := range m.Monitor.infoKeys {
+		if val, ok := info[key]; ok {
+			res[key] = val
+		}
+	}
+	return res
+}
@@ -0,0 +1,136 @@
+package anyrl
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMonitorCSV(t *testing.T) {
+	var buf bytes.Buffer
+	monitor, err := NewMonitor(&buf, MonitorCSV, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 3; i++ {
+		wg.Add(1)
+		go func(epLen int) {
+			defer wg.Done()
+			env := monitor.Wrap(&rnnTestEnv{RewardScale: 1, EpLen: epLen,
+				Observation: []float64{1}})
+			if _, err := env.Reset(); err != nil {
+				t.Error(err)
+				return
+			}
+			for {
+				_, _, done, err := env.Step([]float64{0, 0, 1})
+				if err != nil {
+					t.Error(err)
+					return
+				} else if done {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if monitor.NumEpisodes() != 3 {
+		t.Errorf("expected 3 episodes but got %d", monitor.NumEpisodes())
+	}
+	recent := monitor.Recent()
+	if len(recent) != 2 {
+		t.Fatalf("expected 2 recent episodes but got %d", len(recent))
+	}
+	for _, ep := range recent {
+		if ep.Reward != float64(ep.Length*2) {
+			t.Errorf("unexpected episode: %v", ep)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines but got %d", len(lines))
+	}
+	if lines[0] != "reward,length,time" {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+}
+
+func TestMonitorJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	monitor, err := NewMonitor(&buf, MonitorJSONL, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := monitor.Record(&EpisodeStats{Reward: 3, Length: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"reward":3,"length":2,"time":`) {
+		t.Errorf("unexpected output: %s", buf.String())
+	}
+	if monitor.MeanReward() != 3 {
+		t.Errorf("unexpected mean reward: %f", monitor.MeanReward())
+	}
+}
+
+func TestMonitorInfo(t *testing.T) {
+	monitor, err := NewMonitor(nil, MonitorJSONL, 10, "step", "missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := monitor.Wrap(&ObsNormEnv{
+		Env: &MaxStepsEnv{
+			Env:      &infoTestEnv{},
+			MaxSteps: 3,
+		},
+		Stats: &RunningStats{},
+	})
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := env.Step([]float64{0}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := monitor.Recent()
+	if len(recent) != 1 {
+		t.Fatalf("expected 1 episode but got %d", len(recent))
+	}
+	info := recent[0].Info
+	if len(info) != 1 || info["step"] != 3 {
+		t.Errorf("unexpected info: %v", info)
+	}
+}
+
+func TestMonitorInfoKeys(t *testing.T) {
+	for _, keys := range [][]string{{""}, {"a", "a"}, {"reward"}, {"a", "time"}} {
+		if _, err := NewMonitor(nil, MonitorCSV, 10, keys...); err == nil {
+			t.Errorf("keys %v: expected error", keys)
+		}
+	}
+}
+
+// infoTestEnv is an endless StepInfoer which reports the
+// current timestep in its info.
+type infoTestEnv struct {
+	timestep int
+}
+
+func (i *infoTestEnv) Reset() ([]float64, error) {
+	i.timestep = 0
+	return []float64{0}, nil
+}
+
+func (i *infoTestEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	i.timestep++
+	return []float64{float64(i.timestep)}, 1, false, nil
+}
+
+func (i *infoTestEnv) StepInfo() interface{} {
+	return map[string]interface{}{"step": i.timestep}
+}
@@ -0,0 +1,342 @@
+package anyrl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/serializer"
+)
+
+func init() {
+	var r RunningStats
+	serializer.RegisterTypedDeserializer(r.SerializerType(), DeserializeRunningStats)
+}
+
+// RunningStats tracks the running mean and variance of
+// every component of a stream of vectors.
+//
+// It is safe to share one RunningStats between many
+// environments, even if they are stepped concurrently
+// (as they are by RNNRoller).
+type RunningStats struct {
+	lock   sync.RWMutex
+	count  float64
+	mean   []float64
+	sqDiff []float64
+	frozen bool
+}
+
+// DeserializeRunningStats deserializes a RunningStats.
+//
+// The resulting statistics are not frozen.
+func DeserializeRunningStats(d []byte) (res *RunningStats, err error) {
+	defer essentials.AddCtxTo("deserialize RunningStats", &err)
+	res = &RunningStats{}
+	err = serializer.DeserializeAny(d, &res.count, &res.mean, &res.sqDiff)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.mean) != len(res.sqDiff) {
+		return nil, errors.New("length mismatch")
+	}
+	if res.count == 0 {
+		// Empty statistics have no dimensionality yet.
+		res.mean, res.sqDiff = nil, nil
+	}
+	return res, nil
+}
+
+// Update adds a vector to the statistics.
+//
+// This has no effect if the statistics are frozen.
+//
+// Update panics if the vector's size differs from the
+// size of previous vectors.
+func (r *RunningStats) Update(vec []float64) {
+	if err := r.update(vec); err != nil {
+		panic(err)
+	}
+}
+
+func (r *RunningStats) update(vec []float64) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.frozen {
+		return nil
+	}
+	if r.mean == nil {
+		r.mean = make([]float64, len(vec))
+		r.sqDiff = make([]float64, len(vec))
+	} else if len(vec) != len(r.mean) {
+		return sizeMismatchErr(len(vec), len(r.mean))
+	}
+	r.count++
+	for i, x := range vec {
+		delta := x - r.mean[i]
+		r.mean[i] += delta / r.count
+		r.sqDiff[i] += delta * (x - r.mean[i])
+	}
+	return nil
+}
+
+// Merge adds the statistics from other into r, as if
+// every vector passed to other had been passed to r.
+//
+// This has no effect if r is frozen.
+func (r *RunningStats) Merge(other *RunningStats) {
+	count, mean, sqDiff := other.snapshot()
+	if count == 0 {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.frozen {
+		return
+	}
+	if r.count == 0 {
+		r.count = count
+		r.mean = mean
+		r.sqDiff = sqDiff
+		return
+	} else if len(mean) != len(r.mean) {
+		panic(sizeMismatchErr(len(mean), len(r.mean)))
+	}
+
+	total := r.count + count
+	for i, m := range mean {
+		delta := m - r.mean[i]
+		r.mean[i] += delta * count / total
+		r.sqDiff[i] += sqDiff[i] + delta*delta*r.count*count/total
+	}
+	r.count = total
+}
+
+// Count returns the number of vectors that have been
+// added to the statistics.
+func (r *RunningStats) Count() float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.count
+}
+
+// Mean returns the mean of every vector component.
+//
+// The result is nil if no vectors have been seen.
+func (r *RunningStats) Mean() []float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.mean == nil {
+		return nil
+	}
+	return append([]float64{}, r.mean...)
+}
+
+// Variance returns the variance of every vector
+// component.
+//
+// The result is nil if no vectors have been seen.
+func (r *RunningStats) Variance() []float64 {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.mean == nil {
+		return nil
+	}
+	res := make([]float64, len(r.sqDiff))
+	for i, x := range r.sqDiff {
+		res[i] = x / r.count
+	}
+	return res
+}
+
+// Snapshot returns the count, mean, and variance of the
+// statistics at a single point in time.
+//
+// Unlike separate calls to Count, Mean, and Variance, the
+// results are consistent with each other even if the
+// statistics are being updated concurrently.
+//
+// The mean and variance are nil if no vectors have been
+// seen.
+func (r *RunningStats) Snapshot() (count float64, mean, variance []float64) {
+	count, mean, sqDiff := r.snapshot()
+	if count == 0 {
+		return 0, nil, nil
+	}
+	variance = make([]float64, len(sqDiff))
+	for i, x := range sqDiff {
+		variance[i] = x / count
+	}
+	return count, mean, variance
+}
+
+// Normalize subtracts the mean from a vector and divides
+// it by the standard deviation.
+//
+// If clip is non-zero, the resulting components are
+// clipped to the range [-clip, clip].
+//
+// If no vectors have been seen, vec is returned as-is.
+//
+// Normalize panics if the vector's size differs from the
+// size of the vectors in the statistics.
+func (r *RunningStats) Normalize(vec []float64, clip float64) []float64 {
+	res, err := r.normalize(vec, clip)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+func (r *RunningStats) normalize(vec []float64, clip float64) ([]float64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if r.mean == nil {
+		return vec, nil
+	} else if len(vec) != len(r.mean) {
+		return nil, sizeMismatchErr(len(vec), len(r.mean))
+	}
+	res := make([]float64, len(vec))
+	for i, x := range vec {
+		std := math.Sqrt(r.sqDiff[i]/r.count + 1e-8)
+		res[i] = (x - r.mean[i]) / std
+		if clip != 0 {
+			res[i] = math.Max(-clip, math.Min(clip, res[i]))
+		}
+	}
+	return res, nil
+}
+
+// Freeze prevents further updates to the statistics.
+//
+// This is useful during evaluation.
+func (r *RunningStats) Freeze() {
+	r.lock.Lock()
+	r.frozen = true
+	r.lock.Unlock()
+}
+
+// Unfreeze undoes the effect of Freeze.
+func (r *RunningStats) Unfreeze() {
+	r.lock.Lock()
+	r.frozen = false
+	r.lock.Unlock()
+}
+
+// Frozen returns whether or not the statistics are
+// frozen.
+func (r *RunningStats) Frozen() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.frozen
+}
+
+// SerializerType returns the unique ID used to serialize
+// a RunningStats with the serializer package.
+func (r *RunningStats) SerializerType() string {
+	return "github.com/unixpickle/anyrl.RunningStats"
+}
+
+// Serialize serializes the statistics.
+//
+// Whether or not the statistics are frozen is not saved.
+func (r *RunningStats) Serialize() ([]byte, error) {
+	count, mean, sqDiff := r.snapshot()
+	return serializer.SerializeAny(count, mean, sqDiff)
+}
+
+func (r *RunningStats) snapshot() (count float64, mean, sqDiff []float64) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.count, append([]float64{}, r.mean...), append([]float64{}, r.sqDiff...)
+}
+
+func sizeMismatchErr(actual, expected int) error {
+	return fmt.Errorf("vector size mismatch: got %d but expected %d", actual, expected)
+}
+
+// ObsNormEnv wraps an Env and normalizes its observations
+// using running statistics.
+//
+// Every observation (including the ones from Reset) is
+// added to Stats before it is normalized.
+// To use the same normalization for a batch of
+// environments, give them all the same Stats.
+//
+// If an observation's size does not match the size of
+// the observations in Stats, Reset and Step return an
+// error.
+type ObsNormEnv struct {
+	Env
+
+	// Stats stores the observation statistics.
+	// It must be non-nil.
+	Stats *RunningStats
+
+	// Clip, if non-zero, is the maximum absolute value of
+	// a normalized observation component.
+	Clip float64
+}
+
+// Reset resets the environment.
+func (o *ObsNormEnv) Reset() ([]float64, error) {
+	return o.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (o *ObsNormEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	obs, err := EnvResetCtx(ctx, o.Env)
+	if err != nil {
+		return nil, err
+	}
+	return o.normalize(obs)
+}
+
+// Step takes a step in the environment.
+func (o *ObsNormEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return o.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (o *ObsNormEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	obs, rew, done, err := EnvStepCtx(ctx, o.Env, action)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	obs, err = o.normalize(obs)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return obs, rew, done, nil
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (o *ObsNormEnv) Truncated() bool {
+	return EnvTruncated(o.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (o *ObsNormEnv) StepInfo() interface{} {
+	return EnvStepInfo(o.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (o *ObsNormEnv) Seed(seed int64) {
+	SeedEnv(o.Env, seed)
+}
+
+func (o *ObsNormEnv) normalize(obs []float64) (res []float64, err error) {
+	defer essentials.AddCtxTo("normalize observation", &err)
+	if err := o.Stats.update(obs); err != nil {
+		return nil, err
+	}
+	return o.Stats.normalize(obs, o.Clip)
+}
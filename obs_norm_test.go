@@ -0,0 +1,129 @@
+package anyrl
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/serializer"
+)
+
+func TestRunningStatsMerge(t *testing.T) {
+	joined := &RunningStats{}
+	parts := []*RunningStats{{}, {}, {}}
+	for i := 0; i < 30; i++ {
+		vec := []float64{rand.NormFloat64()*3 + 1, rand.NormFloat64() - 2}
+		joined.Update(vec)
+		parts[i%len(parts)].Update(vec)
+	}
+
+	merged := &RunningStats{}
+	for _, part := range parts {
+		merged.Merge(part)
+	}
+
+	if merged.Count() != joined.Count() {
+		t.Errorf("expected count %f but got %f", joined.Count(), merged.Count())
+	}
+	testVecsClose(t, "mean", joined.Mean(), merged.Mean())
+	testVecsClose(t, "variance", joined.Variance(), merged.Variance())
+}
+
+func TestRunningStatsSnapshot(t *testing.T) {
+	stats := &RunningStats{}
+	if count, mean, variance := stats.Snapshot(); count != 0 || mean != nil ||
+		variance != nil {
+		t.Error("expected empty snapshot")
+	}
+	stats.Update([]float64{1, 2})
+	stats.Update([]float64{3, 6})
+	count, mean, variance := stats.Snapshot()
+	if count != 2 {
+		t.Errorf("expected count 2 but got %f", count)
+	}
+	testVecsClose(t, "mean", []float64{2, 4}, mean)
+	testVecsClose(t, "variance", []float64{1, 4}, variance)
+}
+
+func TestRunningStatsSerialize(t *testing.T) {
+	stats := &RunningStats{}
+	for i := 0; i < 10; i++ {
+		stats.Update([]float64{rand.NormFloat64(), rand.NormFloat64()})
+	}
+	data, err := serializer.SerializeAny(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded *RunningStats
+	if err := serializer.DeserializeAny(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	testVecsClose(t, "mean", stats.Mean(), decoded.Mean())
+	testVecsClose(t, "variance", stats.Variance(), decoded.Variance())
+}
+
+func TestRunningStatsEmpty(t *testing.T) {
+	stats := &RunningStats{}
+	if stats.Mean() != nil || stats.Variance() != nil {
+		t.Error("expected nil statistics")
+	}
+
+	data, err := serializer.SerializeAny(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded *RunningStats
+	if err := serializer.DeserializeAny(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Mean() != nil || decoded.Variance() != nil {
+		t.Error("expected nil statistics after decoding")
+	}
+	decoded.Update([]float64{1, 2})
+	testVecsClose(t, "mean", []float64{1, 2}, decoded.Mean())
+}
+
+func TestRunningStatsFreeze(t *testing.T) {
+	stats := &RunningStats{}
+	stats.Update([]float64{1, 2})
+	stats.Update([]float64{3, 6})
+	stats.Freeze()
+	stats.Update([]float64{100, 100})
+	testVecsClose(t, "mean", []float64{2, 4}, stats.Mean())
+
+	actual := stats.Normalize([]float64{3, 100}, 5)
+	testVecsClose(t, "normalized", []float64{1, 5}, actual)
+}
+
+func TestObsNormEnvSizeMismatch(t *testing.T) {
+	stats := &RunningStats{}
+	stats.Update([]float64{1, 2})
+	env := &ObsNormEnv{Env: &maskTestEnv{}, Stats: stats}
+	if _, err := env.Reset(); err == nil {
+		t.Error("expected error from Reset")
+	}
+	if _, _, _, err := env.Step([]float64{0}); err == nil {
+		t.Error("expected error from Step")
+	}
+
+	stats.Freeze()
+	if _, err := env.Reset(); err == nil {
+		t.Error("expected error from Reset with frozen stats")
+	}
+	if stats.Count() != 1 {
+		t.Errorf("unexpected count: %f", stats.Count())
+	}
+}
+
+func testVecsClose(t *testing.T, name string, expected, actual []float64) {
+	if len(expected) != len(actual) {
+		t.Errorf("%s: expected %v but got %v", name, expected, actual)
+		return
+	}
+	for i, x := range expected {
+		if math.Abs(x-actual[i]) > 1e-5 {
+			t.Errorf("%s: expected %v but got %v", name, expected, actual)
+			return
+		}
+	}
+}
@@ -12,6 +12,10 @@ import (
 type FracReducer struct {
 	Frac float64
 
+	// Rand is used to select rollouts.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
 	// These TapeMakers are called to produce caches of
 	// the reduced tapes.
 	// If a TapeMaker is nil, no cache is used for the
@@ -34,18 +38,48 @@ type FracReducer struct {
 func (f *FracReducer) Reduce(r *RolloutSet) *RolloutSet {
 	numSeqs := len(r.Rewards)
 	numSelected := int(math.Ceil(f.Frac * float64(numSeqs)))
-	indices := rand.Perm(numSeqs)[:numSelected]
+	var indices []int
+	if f.Rand == nil {
+		indices = rand.Perm(numSeqs)[:numSelected]
+	} else {
+		indices = f.Rand.Perm(numSeqs)[:numSelected]
+	}
 	present := make([]bool, numSeqs)
 	for _, j := range indices {
 		present[j] = true
 	}
+	return reduceRollouts(r, present, f.MakeInputTape, f.MakeActionTape,
+		f.MakeAgentOutTape)
+}
+
+// reduceRollouts selects the episodes from a RolloutSet
+// which are marked as present.
+func reduceRollouts(r *RolloutSet, present []bool, makeInput, makeAction,
+	makeAgentOut TapeMaker) *RolloutSet {
+	numSeqs := len(r.Rewards)
 	res := &RolloutSet{
-		Inputs:  reduceTape(f.MakeInputTape, r.Inputs, present),
-		Actions: reduceTape(f.MakeActionTape, r.Actions, present),
+		Inputs:  reduceTape(makeInput, r.Inputs, present),
+		Actions: reduceTape(makeAction, r.Actions, present),
 		Rewards: r.Rewards.Reduce(present),
 	}
 	if r.AgentOuts != nil {
-		res.AgentOuts = reduceTape(f.MakeAgentOutTape, r.AgentOuts, present)
+		res.AgentOuts = reduceTape(makeAgentOut, r.AgentOuts, present)
+	}
+	if r.Truncated != nil {
+		res.Truncated = make([]bool, numSeqs)
+		res.FinalObs = make([][]float64, numSeqs)
+		for i, p := range present {
+			if p && r.Truncated[i] {
+				res.Truncated[i] = true
+				res.FinalObs[i] = r.FinalObs[i]
+			}
+		}
+	}
+	if r.Continued != nil {
+		res.Continued = make([]bool, numSeqs)
+		for i, p := range present {
+			res.Continued[i] = p && r.Continued[i]
+		}
 	}
 	return res
 }
@@ -0,0 +1,128 @@
+package anyrl
+
+import (
+	"context"
+	"math"
+)
+
+// RewardScaleEnv wraps an Env and divides its rewards by
+// a running standard deviation of the discounted return.
+//
+// The rewards are not shifted, since doing so would change
+// the incentives of the agent.
+//
+// The scale starts from a prior with unit variance, which
+// counts as a single observed return and is quickly
+// outweighed by the real returns.
+// This prevents the first rewards from being blown up by
+// a near-zero variance.
+//
+// To use the same scale for a batch of environments, give
+// them all the same Stats.
+type RewardScaleEnv struct {
+	Env
+
+	// Stats stores the statistics of the discounted
+	// return, which are updated at every step.
+	// It must be non-nil.
+	Stats *RunningStats
+
+	// Discount is the discount factor for the return.
+	//
+	// If 0, no discount is used, like for the Discount
+	// fields in anypg.
+	// Thus, the zero value scales by the undiscounted
+	// return, not by individual rewards.
+	Discount float64
+
+	// Clip, if non-zero, is the maximum absolute value of
+	// a scaled reward.
+	Clip float64
+
+	discounted float64
+	epReturn   float64
+}
+
+// Reset resets the environment.
+func (r *RewardScaleEnv) Reset() ([]float64, error) {
+	return r.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (r *RewardScaleEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	r.discounted = 0
+	r.epReturn = 0
+	return EnvResetCtx(ctx, r.Env)
+}
+
+// Step takes a step in the environment.
+func (r *RewardScaleEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return r.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (r *RewardScaleEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	obs, rew, done, err := EnvStepCtx(ctx, r.Env, action)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	r.epReturn += rew
+
+	discount := r.Discount
+	if discount == 0 {
+		discount = 1
+	}
+	r.discounted = r.discounted*discount + rew
+	r.Stats.Update([]float64{r.discounted})
+	if done {
+		r.discounted = 0
+	}
+
+	rew /= math.Sqrt(r.variance() + 1e-8)
+	if r.Clip != 0 {
+		rew = math.Max(-r.Clip, math.Min(r.Clip, rew))
+	}
+	return obs, rew, done, nil
+}
+
+// variance computes the variance of the discounted return,
+// combining Stats with a zero-mean, unit-variance prior.
+func (r *RewardScaleEnv) variance() float64 {
+	const priorCount = 1
+	count, mean, variance := r.Stats.Snapshot()
+	if count == 0 {
+		return 1
+	}
+	total := count + priorCount
+	sqDiff := variance[0]*count + priorCount +
+		mean[0]*mean[0]*count*priorCount/total
+	return sqDiff / total
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (r *RewardScaleEnv) Truncated() bool {
+	return EnvTruncated(r.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (r *RewardScaleEnv) StepInfo() interface{} {
+	return EnvStepInfo(r.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (r *RewardScaleEnv) Seed(seed int64) {
+	SeedEnv(r.Env, seed)
+}
+
+// EpisodeReturn returns the total unscaled reward since
+// the last call to Reset.
+//
+// Once an episode is done, this is the true return of the
+// episode, which is useful for logging.
+func (r *RewardScaleEnv) EpisodeReturn() float64 {
+	return r.epReturn
+}
@@ -0,0 +1,57 @@
+package anyrl
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRewardScaleEnv(t *testing.T) {
+	env := &RewardScaleEnv{
+		Env:      &rnnTestEnv{RewardScale: 1, EpLen: 5, Observation: []float64{1}},
+		Stats:    &RunningStats{},
+		Discount: 0.5,
+	}
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	var rawTotal, discounted float64
+	var returns []float64
+	for i := 0; i < 5; i++ {
+		_, rew, done, err := env.Step([]float64{0, 0, 3, 0})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rawTotal += 2
+		if done != (i == 4) {
+			t.Errorf("step %d: unexpected done value %v", i, done)
+		}
+		discounted = discounted*0.5 + 2
+		returns = append(returns, discounted)
+		std := math.Sqrt(priorVariance(returns) + 1e-8)
+		if math.Abs(rew-2/std) > 1e-5 {
+			t.Errorf("step %d: expected reward %f but got %f", i, 2/std, rew)
+		}
+		if i == 0 && math.Abs(rew-2/math.Sqrt(1.5)) > 1e-5 {
+			t.Errorf("unexpected first reward: %f", rew)
+		}
+	}
+	if env.EpisodeReturn() != rawTotal {
+		t.Errorf("expected return %f but got %f", rawTotal, env.EpisodeReturn())
+	}
+}
+
+// priorVariance computes the variance of values with an
+// extra pseudo-sample for a zero-mean, unit-variance
+// prior.
+func priorVariance(values []float64) float64 {
+	n := float64(len(values)) + 1
+	var mean float64
+	for _, x := range values {
+		mean += x / n
+	}
+	sqDiff := 1 + mean*mean
+	for _, x := range values {
+		sqDiff += (x - mean) * (x - mean)
+	}
+	return sqDiff / n
+}
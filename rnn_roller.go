@@ -1,7 +1,8 @@
 package anyrl
 
 import (
-	"sync"
+	"context"
+	"math/rand"
 
 	"github.com/unixpickle/anydiff/anyseq"
 	"github.com/unixpickle/anynet"
@@ -20,6 +21,12 @@ type TapeMaker func(c anyvec.Creator) (tape lazyseq.Tape,
 
 // RNNRoller runs RNN agents through environments and
 // saves the results to RolloutSets.
+//
+// To use action masks, wrap the environments in MaskEnv
+// and the Block in a MaskBlock, and use a MaskedSoftmax
+// action space.
+// The masks then reach the ActionSpace through the
+// Block's outputs.
 type RNNRoller struct {
 	Block       anyrnn.Block
 	ActionSpace Sampler
@@ -40,10 +47,47 @@ type RNNRoller struct {
 	MakeInputTape    TapeMaker
 	MakeActionTape   TapeMaker
 	MakeAgentOutTape TapeMaker
+
+	// Rand, if non-nil, is used to seed every environment
+	// which implements Seeder before each rollout.
+	//
+	// Together with a seeded ActionSpace, this makes
+	// rollouts reproducible.
+	Rand *rand.Rand
+
+	// UseMode, if true, indicates that actions should be
+	// selected with the ActionSpace's Mode method instead
+	// of being sampled.
+	// This is useful for evaluating trained policies.
+	//
+	// If UseMode is set, ActionSpace must be a Moder.
+	UseMode bool
 }
 
 // Rollout produces one rollout per environment.
 func (r *RNNRoller) Rollout(envs ...Env) (rollouts *RolloutSet, err error) {
+	return r.RolloutBatchCtx(context.Background(), EnvBatch(envs...))
+}
+
+// RolloutCtx is like Rollout, but it fails early if the
+// context is done.
+//
+// If the context's deadline is exceeded, the resulting
+// error is ErrEnvTimeout with added context.
+func (r *RNNRoller) RolloutCtx(ctx context.Context, envs ...Env) (*RolloutSet, error) {
+	return r.RolloutBatchCtx(ctx, EnvBatch(envs...))
+}
+
+// RolloutBatch produces one rollout per environment in a
+// BatchEnv.
+func (r *RNNRoller) RolloutBatch(envs BatchEnv) (*RolloutSet, error) {
+	return r.RolloutBatchCtx(context.Background(), envs)
+}
+
+// RolloutBatchCtx is like RolloutBatch, but it fails
+// early if the context is done.
+func (r *RNNRoller) RolloutBatchCtx(ctx context.Context,
+	envs BatchEnv) (rollouts *RolloutSet, err error) {
 	defer essentials.AddCtxTo("rollout RNN", &err)
 
 	c := r.creator()
@@ -57,28 +101,33 @@ func (r *RNNRoller) Rollout(envs ...Env) (rollouts *RolloutSet, err error) {
 		close(agentOutCh)
 	}()
 
-	rewards, err := r.rolloutChans(inputCh, actionCh, agentOutCh, envs)
-	if err != nil {
-		return nil, err
-	}
-
-	return &RolloutSet{
+	rollouts = &RolloutSet{
 		Inputs:    inputs,
 		Actions:   actions,
 		AgentOuts: agentOuts,
-		Rewards:   rewards,
-	}, nil
+	}
+	if err := r.rolloutChans(ctx, inputCh, actionCh, agentOutCh, envs, rollouts); err != nil {
+		return nil, err
+	}
+
+	return rollouts, nil
 }
 
-func (r *RNNRoller) rolloutChans(inputCh, actionCh, agentOutCh chan<- *anyseq.Batch,
-	envs []Env) (Rewards, error) {
-	if len(envs) == 0 {
-		return nil, nil
+// rolloutChans runs the environments and fills in the
+// Rewards, Truncated, and FinalObs fields of res.
+func (r *RNNRoller) rolloutChans(ctx context.Context,
+	inputCh, actionCh, agentOutCh chan<- *anyseq.Batch, envs BatchEnv,
+	res *RolloutSet) error {
+	if envs.NumEnvs() == 0 {
+		return nil
+	}
+	if r.Rand != nil {
+		SeedBatchEnv(envs, r.Rand)
 	}
 
-	initBatch, err := rolloutReset(r.creator(), envs)
+	initBatch, err := BatchResetCtx(ctx, envs, r.creator())
 	if err != nil {
-		return nil, err
+		return err
 	}
 	rewards := make(Rewards, len(initBatch.Present))
 
@@ -93,16 +142,17 @@ func (r *RNNRoller) rolloutChans(inputCh, actionCh, agentOutCh chan<- *anyseq.Ba
 		blockRes := r.Block.Step(state, inBatch.Packed)
 		state = blockRes.State()
 
-		out := r.ActionSpace.Sample(blockRes.Output(), inBatch.NumPresent())
+		out := r.selectActions(blockRes.Output(), inBatch.NumPresent())
 		actionBatch := &anyseq.Batch{Packed: out, Present: inBatch.Present}
 
 		actionCh <- actionBatch
 		agentOutCh <- &anyseq.Batch{Packed: blockRes.Output(), Present: inBatch.Present}
 
 		var rewardBatch []float64
-		inBatch, rewardBatch, err = rolloutStep(actionBatch, envs)
+		var finalObs [][]float64
+		inBatch, rewardBatch, finalObs, err = BatchStepCtx(ctx, envs, actionBatch)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
 		for i, pres := range actionBatch.Present {
@@ -110,10 +160,26 @@ func (r *RNNRoller) rolloutChans(inputCh, actionCh, agentOutCh chan<- *anyseq.Ba
 				rewards[i] = append(rewards[i], rewardBatch[0])
 				rewardBatch = rewardBatch[1:]
 			}
+			if finalObs[i] != nil {
+				if res.Truncated == nil {
+					res.Truncated = make([]bool, envs.NumEnvs())
+					res.FinalObs = make([][]float64, envs.NumEnvs())
+				}
+				res.Truncated[i] = true
+				res.FinalObs[i] = finalObs[i]
+			}
 		}
 	}
 
-	return rewards, nil
+	res.Rewards = rewards
+	return nil
+}
+
+func (r *RNNRoller) selectActions(params anyvec.Vector, batch int) anyvec.Vector {
+	if r.UseMode {
+		return r.ActionSpace.(Moder).Mode(params, batch)
+	}
+	return r.ActionSpace.Sample(params, batch)
 }
 
 func (r *RNNRoller) creator() anyvec.Creator {
@@ -124,89 +190,6 @@ func (r *RNNRoller) creator() anyvec.Creator {
 	}
 }
 
-func rolloutReset(c anyvec.Creator, envs []Env) (*anyseq.Batch, error) {
-	initBatch := &anyseq.Batch{
-		Present: make([]bool, len(envs)),
-	}
-
-	var allObs []float64
-	for i, e := range envs {
-		obs, err := e.Reset()
-		if err != nil {
-			return nil, err
-		}
-		initBatch.Present[i] = true
-		allObs = append(allObs, obs...)
-	}
-
-	initBatch.Packed = anyvec.Make(c, allObs)
-
-	return initBatch, nil
-}
-
-func rolloutStep(actions *anyseq.Batch, envs []Env) (obs *anyseq.Batch,
-	rewards []float64, err error) {
-	c := actions.Packed.Creator()
-	obs = &anyseq.Batch{
-		Present: make([]bool, len(actions.Present)),
-	}
-	var splitActions [][]float64
-	var presentEnvs []Env
-
-	actionChunkSize := actions.Packed.Len() / actions.NumPresent()
-	var actionOffset int
-	actionSlice := c.Float64Slice(actions.Packed.Data())
-	for i, pres := range actions.Present {
-		if pres {
-			action := actionSlice[actionOffset : actionOffset+actionChunkSize]
-			actionOffset += actionChunkSize
-			splitActions = append(splitActions, action)
-			presentEnvs = append(presentEnvs, envs[i])
-		}
-	}
-
-	obsVecs, rewards, dones, errs := batchStep(presentEnvs, splitActions)
-
-	var presentIdx int
-	var joinObs []float64
-	for i, pres := range actions.Present {
-		if !pres {
-			continue
-		}
-		obsVec, done, err := obsVecs[presentIdx], dones[presentIdx], errs[presentIdx]
-		presentIdx++
-		if err != nil {
-			return nil, nil, err
-		}
-		if !done {
-			obs.Present[i] = true
-			joinObs = append(joinObs, obsVec...)
-		}
-	}
-
-	obs.Packed = anyvec.Make(c, joinObs)
-
-	return
-}
-
-func batchStep(envs []Env, actions [][]float64) (obs [][]float64,
-	rewards []float64, done []bool, err []error) {
-	obs = make([][]float64, len(envs))
-	rewards = make([]float64, len(envs))
-	done = make([]bool, len(envs))
-	err = make([]error, len(envs))
-	var wg sync.WaitGroup
-	for i, e := range envs {
-		wg.Add(1)
-		go func(i int, e Env) {
-			defer wg.Done()
-			obs[i], rewards[i], done[i], err[i] = e.Step(actions[i])
-		}(i, e)
-	}
-	wg.Wait()
-	return
-}
-
 func makeTape(c anyvec.Creator, maker TapeMaker) (lazyseq.Tape, chan<- *anyseq.Batch) {
 	if maker != nil {
 		return maker(c)
@@ -89,6 +89,50 @@ func TestRNNRoller(t *testing.T) {
 	}
 }
 
+func TestRNNRollerTruncation(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	roller := &RNNRoller{
+		Block:       anyrnn.NewLSTM(c, 2, 3),
+		ActionSpace: Softmax{},
+	}
+	envs := []Env{
+		&MaxStepsEnv{
+			Env:      &rnnTestEnv{EpLen: 10, Observation: []float64{1, 2}},
+			MaxSteps: 4,
+		},
+		&MaxStepsEnv{
+			Env:      &rnnTestEnv{EpLen: 3, Observation: []float64{3, 4}},
+			MaxSteps: 4,
+		},
+		&MaxStepsEnv{
+			Env:      &rnnTestEnv{EpLen: 4, Observation: []float64{5, 6}},
+			MaxSteps: 4,
+		},
+	}
+	rollouts, err := roller.Rollout(envs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rollouts.Truncated, []bool{true, false, false}) {
+		t.Errorf("unexpected truncation flags: %v", rollouts.Truncated)
+	}
+	expectedFinal := [][]float64{{4, 8}, nil, nil}
+	if !reflect.DeepEqual(rollouts.FinalObs, expectedFinal) {
+		t.Errorf("expected final observations %v but got %v", expectedFinal,
+			rollouts.FinalObs)
+	}
+
+	var numSteps int
+	for batch := range rollouts.BootstrapInputs().ReadTape(0, -1) {
+		if batch.Present[0] {
+			numSteps++
+		}
+	}
+	if numSteps != 5 {
+		t.Errorf("bootstrap inputs should have 5 steps but got %d", numSteps)
+	}
+}
+
 // rnnTestEnv is a deterministic environment with
 // controllable behavior, making it ideal for testing
 // rollouts.
@@ -127,3 +171,106 @@ func (r *rnnTestEnv) obsVec() []float64 {
 	}
 	return res
 }
+
+func TestRNNRollerSeed(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	block := anyrnn.NewLSTM(c, 3, 4)
+	rollout := func() []float64 {
+		roller := &RNNRoller{
+			Block:       block,
+			ActionSpace: Softmax{Rand: rand.New(rand.NewSource(1337))},
+			Rand:        rand.New(rand.NewSource(42)),
+		}
+		envs := make([]Env, 3)
+		for i := range envs {
+			envs[i] = &ObsNormEnv{
+				Env: &noisyTestEnv{
+					rnnTestEnv: rnnTestEnv{
+						RewardScale: 1,
+						EpLen:       10,
+						Observation: []float64{1, 2, 3},
+					},
+				},
+				Stats: &RunningStats{},
+			}
+		}
+		rollouts, err := roller.Rollout(envs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data []float64
+		for batch := range rollouts.Inputs.ReadTape(0, -1) {
+			data = append(data, c.Float64Slice(batch.Packed.Data())...)
+		}
+		for batch := range rollouts.Actions.ReadTape(0, -1) {
+			data = append(data, c.Float64Slice(batch.Packed.Data())...)
+		}
+		return data
+	}
+	if !reflect.DeepEqual(rollout(), rollout()) {
+		t.Error("seeded rollouts differ")
+	}
+}
+
+// noisyTestEnv is an rnnTestEnv which adds noise to its
+// observations.
+//
+// Unless it is seeded, the noise comes from the global
+// math/rand generator.
+type noisyTestEnv struct {
+	rnnTestEnv
+
+	rand *rand.Rand
+}
+
+func (n *noisyTestEnv) Reset() ([]float64, error) {
+	obs, err := n.rnnTestEnv.Reset()
+	return n.addNoise(obs), err
+}
+
+func (n *noisyTestEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	obs, rew, done, err := n.rnnTestEnv.Step(action)
+	return n.addNoise(obs), rew, done, err
+}
+
+func (n *noisyTestEnv) Seed(seed int64) {
+	n.rand = rand.New(rand.NewSource(seed))
+}
+
+func (n *noisyTestEnv) addNoise(obs []float64) []float64 {
+	for i := range obs {
+		if n.rand == nil {
+			obs[i] += rand.NormFloat64()
+		} else {
+			obs[i] += n.rand.NormFloat64()
+		}
+	}
+	return obs
+}
+
+func TestRNNRollerMode(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	roller := &RNNRoller{
+		Block:       anyrnn.NewLSTM(c, 3, 4),
+		ActionSpace: Softmax{},
+		UseMode:     true,
+	}
+	envs := make([]Env, 3)
+	for i := range envs {
+		envs[i] = &rnnTestEnv{
+			RewardScale: 1,
+			EpLen:       10,
+			Observation: []float64{1, 2, 3},
+		}
+	}
+	rollouts, err := roller.Rollout(envs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := rollouts.Actions.ReadTape(0, -1)
+	for outs := range rollouts.AgentOuts.ReadTape(0, -1) {
+		expected := Softmax{}.Mode(outs.Packed, outs.NumPresent())
+		actual := <-actions
+		assertSimilar(t, actual.Packed, expected)
+	}
+}
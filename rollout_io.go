@@ -0,0 +1,178 @@
+package anyrl
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/anyvec/anyvec32"
+	"github.com/unixpickle/anyvec/anyvec64"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/lazyseq"
+)
+
+// rolloutFormatVersion is incremented whenever the format
+// used by SaveRolloutSet changes.
+const rolloutFormatVersion = 1
+
+type rolloutHeader struct {
+	Version      int
+	NumericType  string
+	Rewards      Rewards
+	Truncated    []bool
+	FinalObs     [][]float64
+	Continued    []bool
+	HasAgentOuts bool
+}
+
+type rolloutBatch struct {
+	Present []bool
+	Data    []float64
+	End     bool
+}
+
+// SaveRolloutSet encodes a RolloutSet to a stream.
+//
+// The tapes are streamed one batch at a time, so the
+// RolloutSet never has to be fully loaded in memory.
+//
+// The numeric type of the creator (float32 or float64)
+// is saved, and every number is stored losslessly.
+func SaveRolloutSet(w io.Writer, r *RolloutSet) (err error) {
+	defer essentials.AddCtxTo("save rollout set", &err)
+
+	numericType, err := creatorNumericType(r.Creator())
+	if err != nil {
+		return err
+	}
+
+	enc := gob.NewEncoder(w)
+	header := &rolloutHeader{
+		Version:      rolloutFormatVersion,
+		NumericType:  numericType,
+		Rewards:      r.Rewards,
+		Truncated:    r.Truncated,
+		FinalObs:     r.FinalObs,
+		Continued:    r.Continued,
+		HasAgentOuts: r.AgentOuts != nil,
+	}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	tapes := []lazyseq.Tape{r.Inputs, r.Actions}
+	if r.AgentOuts != nil {
+		tapes = append(tapes, r.AgentOuts)
+	}
+	for _, tape := range tapes {
+		c := tape.Creator()
+		for batch := range tape.ReadTape(0, -1) {
+			err := enc.Encode(&rolloutBatch{
+				Present: batch.Present,
+				Data:    c.Float64Slice(batch.Packed.Data()),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := enc.Encode(&rolloutBatch{End: true}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadRolloutSet decodes a RolloutSet which was encoded
+// with SaveRolloutSet.
+//
+// If c is nil, the current anyvec32 or anyvec64 creator
+// is used, depending on the saved numeric type.
+//
+// If tm is nil, lazyseq.ReferenceTape is used to store
+// the tapes in memory.
+func LoadRolloutSet(r io.Reader, c anyvec.Creator, tm TapeMaker) (res *RolloutSet,
+	err error) {
+	defer essentials.AddCtxTo("load rollout set", &err)
+
+	dec := gob.NewDecoder(r)
+	var header rolloutHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Version != rolloutFormatVersion {
+		return nil, fmt.Errorf("unsupported version: %d", header.Version)
+	}
+
+	if c == nil {
+		switch header.NumericType {
+		case "float32":
+			c = anyvec32.CurrentCreator()
+		case "float64":
+			c = anyvec64.CurrentCreator()
+		default:
+			return nil, fmt.Errorf("unsupported numeric type: %s", header.NumericType)
+		}
+	}
+
+	res = &RolloutSet{
+		Rewards:   header.Rewards,
+		Truncated: header.Truncated,
+		FinalObs:  header.FinalObs,
+		Continued: header.Continued,
+	}
+	for i, obs := range res.FinalObs {
+		// Gob does not distinguish nil from empty slices.
+		if len(obs) == 0 {
+			res.FinalObs[i] = nil
+		}
+	}
+	tapes := []*lazyseq.Tape{&res.Inputs, &res.Actions}
+	if header.HasAgentOuts {
+		tapes = append(tapes, &res.AgentOuts)
+	}
+	for _, tapePtr := range tapes {
+		tape, writer := makeTape(c, tm)
+		err := decodeTape(dec, c, writer)
+		close(writer)
+		if err != nil {
+			return nil, err
+		}
+		*tapePtr = tape
+	}
+
+	return res, nil
+}
+
+func decodeTape(dec *gob.Decoder, c anyvec.Creator, writer chan<- *anyseq.Batch) error {
+	for {
+		var batch rolloutBatch
+		if err := dec.Decode(&batch); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if batch.End {
+			return nil
+		}
+		writer <- &anyseq.Batch{
+			Packed:  c.MakeVectorData(c.MakeNumericList(batch.Data)),
+			Present: batch.Present,
+		}
+	}
+}
+
+func creatorNumericType(c anyvec.Creator) (string, error) {
+	switch c.MakeNumeric(0).(type) {
+	case float32:
+		return "float32", nil
+	case float64:
+		return "float64", nil
+	default:
+		return "", errors.New("unsupported numeric type")
+	}
+}
@@ -0,0 +1,72 @@
+package anyrl
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyvec/anyvec64"
+	"github.com/unixpickle/lazyseq"
+)
+
+func TestSaveLoadRolloutSet(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	roller := &RNNRoller{
+		Block:       anyrnn.NewLSTM(c, 2, 3),
+		ActionSpace: Softmax{},
+	}
+	envs := []Env{
+		&rnnTestEnv{RewardScale: 1, EpLen: 5, Observation: []float64{1, 2}},
+		&MaxStepsEnv{
+			Env:      &rnnTestEnv{EpLen: 10, Observation: []float64{3, 4}},
+			MaxSteps: 3,
+		},
+	}
+	expected, err := roller.Rollout(envs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := SaveRolloutSet(&buf, expected); err != nil {
+		t.Fatal(err)
+	}
+	for _, maker := range []TapeMaker{nil, CompressedTapeMaker(Float64Precision)} {
+		actual, err := LoadRolloutSet(bytes.NewReader(buf.Bytes()), nil, maker)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := actual.Creator().MakeNumeric(0).(float64); !ok {
+			t.Error("unexpected numeric type")
+		}
+		if !reflect.DeepEqual(actual.Rewards, expected.Rewards) {
+			t.Error("rewards do not match")
+		}
+		if !reflect.DeepEqual(actual.Truncated, expected.Truncated) ||
+			!reflect.DeepEqual(actual.FinalObs, expected.FinalObs) {
+			t.Error("truncation info does not match")
+		}
+		tapePairs := [][2]lazyseq.Tape{
+			{actual.Inputs, expected.Inputs},
+			{actual.Actions, expected.Actions},
+			{actual.AgentOuts, expected.AgentOuts},
+		}
+		for i, pair := range tapePairs {
+			actualBatches := pair[0].ReadTape(0, -1)
+			for expBatch := range pair[1].ReadTape(0, -1) {
+				actualBatch, ok := <-actualBatches
+				if !ok {
+					t.Fatalf("tape %d: missing batch", i)
+				}
+				if !reflect.DeepEqual(actualBatch.Present, expBatch.Present) {
+					t.Errorf("tape %d: present mismatch", i)
+				}
+				assertSimilar(t, actualBatch.Packed, expBatch.Packed)
+			}
+			if _, ok := <-actualBatches; ok {
+				t.Errorf("tape %d: extra batch", i)
+			}
+		}
+	}
+}
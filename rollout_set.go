@@ -1,6 +1,7 @@
 package anyrl
 
 import (
+	"github.com/unixpickle/anydiff/anyseq"
 	"github.com/unixpickle/anyvec"
 	"github.com/unixpickle/lazyseq"
 )
@@ -27,6 +28,28 @@ type RolloutSet struct {
 	// This field is mostly meant for agents which are
 	// based on function approximators.
 	AgentOuts lazyseq.Tape
+
+	// Truncated indicates, for each episode, whether the
+	// episode was cut off by a time limit rather than
+	// ending in a terminal state.
+	//
+	// This may be nil if no episodes were truncated.
+	Truncated []bool
+
+	// FinalObs stores, for each truncated episode, the
+	// observation produced by the final step.
+	// Entries for other episodes are nil.
+	//
+	// This may be nil if no episodes were truncated.
+	FinalObs [][]float64
+
+	// Continued indicates, for each episode, whether the
+	// episode started before the RolloutSet did.
+	// This happens when rollouts are split into segments,
+	// as with StreamRoller.
+	//
+	// This may be nil if no episodes were continued.
+	Continued []bool
 }
 
 // PackRolloutSets joins multiple RolloutSets into one
@@ -65,9 +88,62 @@ func PackRolloutSets(c anyvec.Creator, rs []*RolloutSet) *RolloutSet {
 	}
 	res.Rewards = PackRewards(rewards)
 
+	for _, r := range rs {
+		if r.Truncated != nil {
+			packTruncation(res, rs)
+			break
+		}
+	}
+	for _, r := range rs {
+		if r.Continued != nil {
+			for _, r := range rs {
+				for i := range r.Rewards {
+					res.Continued = append(res.Continued, r.EpisodeContinued(i))
+				}
+			}
+			break
+		}
+	}
+
 	return res
 }
 
+func packTruncation(res *RolloutSet, rs []*RolloutSet) {
+	for _, r := range rs {
+		for i := range r.Rewards {
+			res.Truncated = append(res.Truncated, r.EpisodeTruncated(i))
+			if r.EpisodeTruncated(i) {
+				res.FinalObs = append(res.FinalObs, r.FinalObs[i])
+			} else {
+				res.FinalObs = append(res.FinalObs, nil)
+			}
+		}
+	}
+}
+
+// Close closes the tapes in the RolloutSet with
+// CloseTape, returning the first error.
+//
+// This should be called once the rollouts are no longer
+// needed if the tapes hold resources, such as the files
+// created by DiskTapeMaker.
+//
+// A RolloutSet from PackRolloutSets does not own the
+// tapes it reads from, so the original sets should be
+// closed instead.
+func (r *RolloutSet) Close() error {
+	var firstErr error
+	for _, tape := range []lazyseq.Tape{r.Inputs, r.Actions, r.AgentOuts} {
+		if tape == nil {
+			continue
+		}
+		if err := CloseTape(tape); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
 // Creator returns the input tape's creator.
 func (r *RolloutSet) Creator() anyvec.Creator {
 	return r.Inputs.Creator()
@@ -82,3 +158,75 @@ func (r *RolloutSet) NumSteps() int {
 	}
 	return count
 }
+
+// EpisodeTruncated returns true if the episode at the
+// given index was truncated.
+func (r *RolloutSet) EpisodeTruncated(idx int) bool {
+	return r.Truncated != nil && r.Truncated[idx]
+}
+
+// EpisodeContinued returns true if the episode at the
+// given index was continued from a previous RolloutSet.
+func (r *RolloutSet) EpisodeContinued(idx int) bool {
+	return r.Continued != nil && r.Continued[idx]
+}
+
+// BootstrapInputs produces a tape like r.Inputs, except
+// that every truncated episode is extended by an extra
+// timestep containing its final observation.
+//
+// This makes it possible to evaluate a value function at
+// the end of a truncated episode.
+func (r *RolloutSet) BootstrapInputs() lazyseq.Tape {
+	c := r.Creator()
+	res, writer := lazyseq.ReferenceTape(c)
+
+	var maxLen int
+	for _, seq := range r.Rewards {
+		if len(seq) > maxLen {
+			maxLen = len(seq)
+		}
+	}
+
+	go func() {
+		defer close(writer)
+		inputs := r.Inputs.ReadTape(0, -1)
+		defer func() {
+			for _ = range inputs {
+			}
+		}()
+		for t := 0; t <= maxLen; t++ {
+			var packed []float64
+			var size int
+			if t < maxLen {
+				batch := <-inputs
+				packed = c.Float64Slice(batch.Packed.Data())
+				size = len(packed) / batch.NumPresent()
+			}
+			var joined []float64
+			var anyPresent bool
+			present := make([]bool, len(r.Rewards))
+			for i, seq := range r.Rewards {
+				if t < len(seq) {
+					joined = append(joined, packed[:size]...)
+					packed = packed[size:]
+				} else if t == len(seq) && r.EpisodeTruncated(i) {
+					joined = append(joined, r.FinalObs[i]...)
+				} else {
+					continue
+				}
+				present[i] = true
+				anyPresent = true
+			}
+			if !anyPresent {
+				break
+			}
+			writer <- &anyseq.Batch{
+				Packed:  c.MakeVectorData(c.MakeNumericList(joined)),
+				Present: present,
+			}
+		}
+	}()
+
+	return res
+}
@@ -0,0 +1,198 @@
+package anyrl
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/unixpickle/lazyseq"
+)
+
+// A Distribution summarizes a list of values.
+type Distribution struct {
+	Mean   float64 `json:"mean"`
+	Std    float64 `json:"std"`
+	Min    float64 `json:"min"`
+	P10    float64 `json:"p10"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	P90    float64 `json:"p90"`
+	Max    float64 `json:"max"`
+}
+
+// NewDistribution summarizes a list of values.
+//
+// Percentiles are computed with linear interpolation.
+// If there are no values, every field is 0.
+func NewDistribution(values []float64) *Distribution {
+	if len(values) == 0 {
+		return &Distribution{}
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	var sum, sqSum float64
+	for _, x := range sorted {
+		sum += x
+	}
+	mean := sum / float64(len(sorted))
+	for _, x := range sorted {
+		sqSum += (x - mean) * (x - mean)
+	}
+
+	return &Distribution{
+		Mean:   mean,
+		Std:    math.Sqrt(sqSum / float64(len(sorted))),
+		Min:    sorted[0],
+		P10:    percentile(sorted, 0.1),
+		P25:    percentile(sorted, 0.25),
+		Median: percentile(sorted, 0.5),
+		P75:    percentile(sorted, 0.75),
+		P90:    percentile(sorted, 0.9),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// String produces a one-line summary of the distribution.
+func (d *Distribution) String() string {
+	return fmt.Sprintf("mean=%g std=%g min=%g p10=%g p25=%g median=%g p75=%g "+
+		"p90=%g max=%g", d.Mean, d.Std, d.Min, d.P10, d.P25, d.Median, d.P75,
+		d.P90, d.Max)
+}
+
+// RolloutStats summarizes a RolloutSet.
+//
+// It can be printed with String or encoded as JSON for
+// structured logging.
+type RolloutStats struct {
+	NumEpisodes  int `json:"num_episodes"`
+	NumSteps     int `json:"num_steps"`
+	NumTruncated int `json:"num_truncated"`
+
+	Lengths *Distribution `json:"lengths"`
+	Returns *Distribution `json:"returns"`
+
+	// Per-component statistics for the observations and
+	// actions.
+	ObsMean    []float64 `json:"obs_mean"`
+	ObsStd     []float64 `json:"obs_std"`
+	ActionMean []float64 `json:"action_mean"`
+	ActionStd  []float64 `json:"action_std"`
+
+	// ActionCounts counts, for each action component, the
+	// number of timesteps where that component was 1.
+	// For one-hot actions (e.g. from Softmax), this is a
+	// histogram of the actions.
+	//
+	// It is nil unless every action component is 0 or 1.
+	ActionCounts []int `json:"action_counts,omitempty"`
+}
+
+// NewRolloutStats computes statistics for a RolloutSet.
+//
+// Episodes with no timesteps, such as the episodes left
+// out by FracReducer, are ignored.
+func NewRolloutStats(r *RolloutSet) *RolloutStats {
+	res := &RolloutStats{}
+	var lengths, returns []float64
+	for i, rews := range r.Rewards {
+		if len(rews) == 0 {
+			continue
+		}
+		res.NumEpisodes++
+		res.NumSteps += len(rews)
+		if r.EpisodeTruncated(i) {
+			res.NumTruncated++
+		}
+		lengths = append(lengths, float64(len(rews)))
+		var total float64
+		for _, x := range rews {
+			total += x
+		}
+		returns = append(returns, total)
+	}
+	res.Lengths = NewDistribution(lengths)
+	res.Returns = NewDistribution(returns)
+
+	res.ObsMean, res.ObsStd, _ = tapeComponentStats(r.Inputs, false)
+	res.ActionMean, res.ActionStd, res.ActionCounts = tapeComponentStats(r.Actions, true)
+
+	return res
+}
+
+// String produces a multi-line, human-readable summary.
+func (r *RolloutStats) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "episodes=%d steps=%d truncated=%d\n", r.NumEpisodes,
+		r.NumSteps, r.NumTruncated)
+	fmt.Fprintf(&buf, "lengths: %s\n", r.Lengths)
+	fmt.Fprintf(&buf, "returns: %s\n", r.Returns)
+	fmt.Fprintf(&buf, "obs mean: %v\n", r.ObsMean)
+	fmt.Fprintf(&buf, "obs std: %v\n", r.ObsStd)
+	fmt.Fprintf(&buf, "action mean: %v\n", r.ActionMean)
+	fmt.Fprintf(&buf, "action std: %v", r.ActionStd)
+	if r.ActionCounts != nil {
+		fmt.Fprintf(&buf, "\naction counts: %v", r.ActionCounts)
+	}
+	return buf.String()
+}
+
+// tapeComponentStats computes the mean and standard
+// deviation of every vector component in a tape.
+//
+// If countOnes is true, it also counts the number of
+// times each component was 1, unless some component was
+// not 0 or 1.
+func tapeComponentStats(t lazyseq.Tape, countOnes bool) (mean, std []float64,
+	counts []int) {
+	c := t.Creator()
+	stats := &RunningStats{}
+	binary := countOnes
+	for batch := range t.ReadTape(0, -1) {
+		if batch.NumPresent() == 0 {
+			continue
+		}
+		vals := c.Float64Slice(batch.Packed.Data())
+		size := len(vals) / batch.NumPresent()
+		if counts == nil && binary {
+			counts = make([]int, size)
+		}
+		for i := 0; i < batch.NumPresent(); i++ {
+			vec := vals[i*size : (i+1)*size]
+			stats.Update(vec)
+			if !binary {
+				continue
+			}
+			for j, x := range vec {
+				if x == 1 {
+					counts[j]++
+				} else if x != 0 {
+					binary = false
+				}
+			}
+		}
+	}
+	if !binary {
+		counts = nil
+	}
+
+	mean = stats.Mean()
+	for _, v := range stats.Variance() {
+		std = append(std, math.Sqrt(v))
+	}
+	return
+}
+
+// percentile computes a percentile of sorted values with
+// linear interpolation.
+func percentile(sorted []float64, frac float64) float64 {
+	idx := frac * float64(len(sorted)-1)
+	lower := int(math.Floor(idx))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	weight := idx - float64(lower)
+	return sorted[lower]*(1-weight) + sorted[lower+1]*weight
+}
@@ -0,0 +1,85 @@
+package anyrl
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec32"
+)
+
+func TestNewDistribution(t *testing.T) {
+	dist := NewDistribution([]float64{4, 1, 3, 2, 5})
+	expected := &Distribution{
+		Mean:   3,
+		Std:    math.Sqrt(2),
+		Min:    1,
+		P10:    1.4,
+		P25:    2,
+		Median: 3,
+		P75:    4,
+		P90:    4.6,
+		Max:    5,
+	}
+	actual := reflect.ValueOf(*dist)
+	exp := reflect.ValueOf(*expected)
+	for i := 0; i < actual.NumField(); i++ {
+		if math.Abs(actual.Field(i).Float()-exp.Field(i).Float()) > 1e-8 {
+			t.Errorf("expected %v but got %v", expected, dist)
+			break
+		}
+	}
+}
+
+func TestNewRolloutStats(t *testing.T) {
+	c := anyvec32.DefaultCreator{}
+	trajs := []*Trajectory{
+		{
+			Observations: [][]float64{{1, 2}, {3, 2}},
+			Actions:      [][]float64{{1, 0, 0}, {0, 0, 1}},
+			Rewards:      []float64{1, 2},
+			Done:         true,
+		},
+		{
+			Observations: [][]float64{{5, 2}, {7, 2}, {9, 2}},
+			Actions:      [][]float64{{0, 0, 1}, {0, 0, 1}, {0, 1, 0}},
+			Rewards:      []float64{-1, 0, 0.5},
+			Done:         true,
+			Truncated:    true,
+			FinalObs:     []float64{11, 2},
+		},
+	}
+	stats := NewRolloutStats(TrajectoryRolloutSet(c, trajs))
+
+	if stats.NumEpisodes != 2 || stats.NumSteps != 5 || stats.NumTruncated != 1 {
+		t.Errorf("unexpected counts: %v", stats)
+	}
+	if stats.Lengths.Min != 2 || stats.Lengths.Max != 3 {
+		t.Errorf("unexpected lengths: %v", stats.Lengths)
+	}
+	if stats.Returns.Min != -0.5 || stats.Returns.Max != 3 {
+		t.Errorf("unexpected returns: %v", stats.Returns)
+	}
+	if math.Abs(stats.ObsMean[0]-5) > 1e-5 || math.Abs(stats.ObsMean[1]-2) > 1e-5 {
+		t.Errorf("unexpected observation mean: %v", stats.ObsMean)
+	}
+	if math.Abs(stats.ObsStd[0]-math.Sqrt(8)) > 1e-5 || math.Abs(stats.ObsStd[1]) > 1e-5 {
+		t.Errorf("unexpected observation std: %v", stats.ObsStd)
+	}
+	if !reflect.DeepEqual(stats.ActionCounts, []int{1, 1, 3}) {
+		t.Errorf("unexpected action counts: %v", stats.ActionCounts)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded RolloutStats
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&decoded, stats) {
+		t.Errorf("JSON round trip failed: %s", data)
+	}
+}
@@ -0,0 +1,288 @@
+package anyrl
+
+import (
+	"context"
+
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/lazyseq"
+)
+
+// StreamRoller runs an RNNRoller's agent through a fixed
+// set of environments for a fixed number of timesteps at
+// a time.
+//
+// Unlike RNNRoller.Rollout, a StreamRoller keeps the
+// environment and RNN states between calls.
+// Environments are reset automatically when they finish
+// an episode, and the RNN state is reset along with them.
+// This makes it possible to train on long or infinite
+// episodes.
+//
+// Each episode in a resulting RolloutSet is a segment of
+// an episode.
+// Segments which are cut off by the end of a rollout are
+// marked as truncated, and their FinalObs can be used for
+// bootstrapping.
+// Segments which started in a previous rollout are
+// marked in the Continued field.
+//
+// The agent outputs in a RolloutSet are computed with the
+// RNN states from the continued episodes.
+// Thus, re-computing the agent outputs from the start
+// state only reproduces them for feed-forward agents or
+// for segments that are not continued.
+//
+// Environments which restart at the same time share a
+// batch of RNN states.
+// Since an anyrnn.State cannot be joined with another
+// State, these batches are never merged; a batch only
+// goes away once all of its environments have restarted.
+// When episode lengths vary, the agent may thus end up
+// running one small batch per environment at every
+// timestep, which is slower than one large batch.
+type StreamRoller struct {
+	// Roller provides the agent and the tape settings.
+	Roller *RNNRoller
+
+	// Envs are the environments to run.
+	// If Roller.Rand is set, it is used to seed Envs
+	// before they are first reset.
+	Envs []Env
+
+	// Horizon is the number of timesteps to run each
+	// environment for in every rollout.
+	Horizon int
+
+	obs        [][]float64
+	midEpisode []bool
+	groups     []*streamGroup
+}
+
+// Rollout runs every environment for Horizon timesteps.
+//
+// The episodes in the result are ordered by environment,
+// and then chronologically.
+func (s *StreamRoller) Rollout() (*RolloutSet, error) {
+	return s.RolloutCtx(context.Background())
+}
+
+// RolloutCtx is like Rollout, but it fails early if the
+// context is done.
+//
+// If a rollout fails, every environment is reset at the
+// start of the next rollout.
+func (s *StreamRoller) RolloutCtx(ctx context.Context) (rollouts *RolloutSet,
+	err error) {
+	defer essentials.AddCtxTo("stream rollout", &err)
+	defer func() {
+		if err != nil {
+			s.obs = nil
+			s.midEpisode = nil
+			s.groups = nil
+		}
+	}()
+
+	if s.obs == nil {
+		if err := s.resetAll(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	c := s.Roller.creator()
+	segments := make([][]*streamSegment, len(s.Envs))
+	for i := range segments {
+		segments[i] = []*streamSegment{{Continued: s.midEpisode[i]}}
+	}
+
+	for t := 0; t < s.Horizon; t++ {
+		actions := make([][]float64, len(s.Envs))
+		for _, g := range s.groups {
+			var packed []float64
+			for _, idx := range g.envs {
+				packed = append(packed, s.obs[idx]...)
+			}
+			res := s.Roller.Block.Step(g.state, c.MakeVectorData(c.MakeNumericList(packed)))
+			g.state = res.State()
+			outs := res.Output()
+			acts := s.Roller.selectActions(outs, len(g.envs))
+			outSlice := c.Float64Slice(outs.Data())
+			actSlice := c.Float64Slice(acts.Data())
+			outSize := len(outSlice) / len(g.envs)
+			actSize := len(actSlice) / len(g.envs)
+			for i, idx := range g.envs {
+				seg := lastSegment(segments[idx])
+				seg.Inputs = append(seg.Inputs, copyVec(s.obs[idx]))
+				seg.AgentOuts = append(seg.AgentOuts, outSlice[i*outSize:(i+1)*outSize])
+				actions[idx] = actSlice[i*actSize : (i+1)*actSize]
+				seg.Actions = append(seg.Actions, actions[idx])
+			}
+		}
+
+		obs, rewards, dones, errs := batchStep(ctx, s.Envs, actions)
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var finished []int
+		for i, done := range dones {
+			seg := lastSegment(segments[i])
+			seg.Rewards = append(seg.Rewards, rewards[i])
+			if done {
+				if EnvTruncated(s.Envs[i]) {
+					seg.Truncated = true
+					seg.FinalObs = copyVec(obs[i])
+				}
+				segments[i] = append(segments[i], &streamSegment{})
+				finished = append(finished, i)
+			} else {
+				s.obs[i] = obs[i]
+				s.midEpisode[i] = true
+			}
+		}
+		if len(finished) > 0 {
+			if err := s.restart(ctx, finished); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for i, segs := range segments {
+		seg := lastSegment(segs)
+		if len(seg.Rewards) > 0 {
+			seg.Truncated = true
+			seg.FinalObs = copyVec(s.obs[i])
+		}
+	}
+
+	return s.rolloutSet(segments), nil
+}
+
+// resetAll resets (and possibly seeds) every environment
+// and the RNN state.
+func (s *StreamRoller) resetAll(ctx context.Context) error {
+	if s.Roller.Rand != nil {
+		for _, env := range s.Envs {
+			SeedEnv(env, s.Roller.Rand.Int63())
+		}
+	}
+	s.obs = make([][]float64, len(s.Envs))
+	s.midEpisode = make([]bool, len(s.Envs))
+	indices := make([]int, len(s.Envs))
+	for i, env := range s.Envs {
+		obs, err := EnvResetCtx(ctx, env)
+		if err != nil {
+			return err
+		}
+		s.obs[i] = obs
+		indices[i] = i
+	}
+	s.groups = []*streamGroup{{envs: indices, state: s.Roller.Block.Start(len(indices))}}
+	return nil
+}
+
+// restart resets the given environments and moves them
+// into a new group with a fresh RNN state.
+func (s *StreamRoller) restart(ctx context.Context, indices []int) error {
+	isFinished := map[int]bool{}
+	for _, idx := range indices {
+		isFinished[idx] = true
+	}
+
+	var newGroups []*streamGroup
+	for _, g := range s.groups {
+		present := make(anyrnn.PresentMap, len(g.envs))
+		var remaining []int
+		for i, idx := range g.envs {
+			if !isFinished[idx] {
+				present[i] = true
+				remaining = append(remaining, idx)
+			}
+		}
+		if len(remaining) == 0 {
+			continue
+		} else if len(remaining) < len(g.envs) {
+			g.state = g.state.Reduce(present)
+			g.envs = remaining
+		}
+		newGroups = append(newGroups, g)
+	}
+
+	for _, idx := range indices {
+		obs, err := EnvResetCtx(ctx, s.Envs[idx])
+		if err != nil {
+			return err
+		}
+		s.obs[idx] = obs
+		s.midEpisode[idx] = false
+	}
+	s.groups = append(newGroups, &streamGroup{
+		envs:  indices,
+		state: s.Roller.Block.Start(len(indices)),
+	})
+
+	return nil
+}
+
+func (s *StreamRoller) rolloutSet(segments [][]*streamSegment) *RolloutSet {
+	c := s.Roller.creator()
+
+	var inputs, actions, agentOuts [][][]float64
+	res := &RolloutSet{}
+	for _, segs := range segments {
+		for _, seg := range segs {
+			if len(seg.Rewards) == 0 {
+				continue
+			}
+			inputs = append(inputs, seg.Inputs)
+			actions = append(actions, seg.Actions)
+			agentOuts = append(agentOuts, seg.AgentOuts)
+			res.Rewards = append(res.Rewards, seg.Rewards)
+			res.Truncated = append(res.Truncated, seg.Truncated)
+			res.FinalObs = append(res.FinalObs, seg.FinalObs)
+			res.Continued = append(res.Continued, seg.Continued)
+		}
+	}
+
+	tapes := []*struct {
+		Field *lazyseq.Tape
+		Maker TapeMaker
+		Seqs  [][][]float64
+	}{
+		{&res.Inputs, s.Roller.MakeInputTape, inputs},
+		{&res.Actions, s.Roller.MakeActionTape, actions},
+		{&res.AgentOuts, s.Roller.MakeAgentOutTape, agentOuts},
+	}
+	for _, tape := range tapes {
+		t, writer := makeTape(c, tape.Maker)
+		writeVectorSeqs(c, writer, tape.Seqs)
+		close(writer)
+		*tape.Field = t
+	}
+
+	return res
+}
+
+// streamGroup is a group of environments which share an
+// RNN state, since they were reset at the same time.
+type streamGroup struct {
+	envs  []int
+	state anyrnn.State
+}
+
+// streamSegment is part of an episode.
+type streamSegment struct {
+	Inputs    [][]float64
+	Actions   [][]float64
+	AgentOuts [][]float64
+	Rewards   []float64
+	Truncated bool
+	FinalObs  []float64
+	Continued bool
+}
+
+func lastSegment(segs []*streamSegment) *streamSegment {
+	return segs[len(segs)-1]
+}
@@ -0,0 +1,85 @@
+package anyrl
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anynet/anyrnn"
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestStreamRoller(t *testing.T) {
+	c := anyvec64.DefaultCreator{}
+	roller := &StreamRoller{
+		Roller: &RNNRoller{
+			Block:       anyrnn.NewLSTM(c, 2, 3),
+			ActionSpace: Softmax{},
+		},
+		Envs: []Env{
+			&rnnTestEnv{RewardScale: 1, EpLen: 5, Observation: []float64{1, 2}},
+			&rnnTestEnv{RewardScale: 1, EpLen: 2, Observation: []float64{3, 4}},
+		},
+		Horizon: 3,
+	}
+
+	rollouts1, err := roller.Rollout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStreamRollouts(t, rollouts1, []int{3, 2, 1}, []bool{true, false, true},
+		[]bool{false, false, false})
+
+	rollouts2, err := roller.Rollout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStreamRollouts(t, rollouts2, []int{2, 1, 1, 2}, []bool{false, true, false, false},
+		[]bool{true, false, true, false})
+
+	// The first segment of the second rollout should pick
+	// up where the first rollout left off.
+	firstInputs := <-rollouts2.Inputs.ReadTape(0, 1)
+	actual := c.Float64Slice(firstInputs.Packed.Slice(0, 2).Data())
+	if !reflect.DeepEqual(actual, rollouts1.FinalObs[0]) {
+		t.Errorf("expected input %v but got %v", rollouts1.FinalObs[0], actual)
+	}
+
+	// The second environment's episode ended exactly at
+	// the end of the second rollout, so its first segment
+	// in the third rollout is not continued.
+	rollouts3, err := roller.Rollout()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkStreamRollouts(t, rollouts3, []int{3, 2, 1}, []bool{true, false, true},
+		[]bool{true, false, false})
+}
+
+func checkStreamRollouts(t *testing.T, r *RolloutSet, lengths []int, truncated,
+	continued []bool) {
+	var actualLengths []int
+	for _, rews := range r.Rewards {
+		actualLengths = append(actualLengths, len(rews))
+	}
+	if !reflect.DeepEqual(actualLengths, lengths) {
+		t.Errorf("expected lengths %v but got %v", lengths, actualLengths)
+	}
+	if !reflect.DeepEqual(r.Truncated, truncated) {
+		t.Errorf("expected truncated %v but got %v", truncated, r.Truncated)
+	}
+	if !reflect.DeepEqual(r.Continued, continued) {
+		t.Errorf("expected continued %v but got %v", continued, r.Continued)
+	}
+	for i, trunc := range truncated {
+		if trunc != (r.FinalObs[i] != nil) {
+			t.Errorf("episode %d: unexpected final observation", i)
+		}
+	}
+	var numSteps int
+	for batch := range r.Inputs.ReadTape(0, -1) {
+		numSteps += batch.NumPresent()
+	}
+	if numSteps != r.NumSteps() {
+		t.Errorf("expected %d inputs but got %d", r.NumSteps(), numSteps)
+	}
+}
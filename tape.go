@@ -0,0 +1,433 @@
+package anyrl
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/lazyseq"
+)
+
+// TapePrecision specifies how a tape stores numbers.
+type TapePrecision int
+
+const (
+	// Float64Precision stores 64-bit floats.
+	// This is lossless for every anyvec.Creator that uses
+	// float32 or float64 numerics.
+	Float64Precision TapePrecision = iota
+
+	// Float32Precision stores 32-bit floats.
+	Float32Precision
+
+	// Float16Precision stores IEEE half-precision floats.
+	// It is lossy, but it works well for observations
+	// like images.
+	Float16Precision
+)
+
+// CompressedTapeMaker creates a TapeMaker for tapes that
+// keep their batches in memory, compressed with DEFLATE.
+//
+// With Float64Precision, the compression is lossless,
+// making it suitable for actions (e.g. one-hot vectors).
+func CompressedTapeMaker(p TapePrecision) TapeMaker {
+	return func(c anyvec.Creator) (lazyseq.Tape, chan<- *anyseq.Batch) {
+		return newStoredTape(c, p, true, &memTapeStorage{})
+	}
+}
+
+// DiskTapeMaker creates a TapeMaker for tapes that store
+// their batches in temporary files.
+//
+// The files are created in dir, or in the default
+// temporary directory if dir is "".
+// If compress is true, each batch is compressed with
+// DEFLATE before it is written.
+//
+// A tape's file should be deleted with CloseTape (or
+// RolloutSet.Close) once the tape is no longer needed.
+// As a backstop, the file is also deleted when the tape
+// is garbage collected, but finalizers may run late or
+// not at all, so this should not be relied upon.
+//
+// Since lazyseq.Tape cannot report errors, I/O errors
+// cause panics.
+func DiskTapeMaker(dir string, p TapePrecision, compress bool) TapeMaker {
+	return func(c anyvec.Creator) (lazyseq.Tape, chan<- *anyseq.Batch) {
+		f, err := ioutil.TempFile(dir, "anyrl-tape")
+		if err != nil {
+			panic(essentials.AddCtx("create tape", err))
+		}
+		storage := &diskTapeStorage{file: f}
+		tape, writer := newStoredTape(c, p, compress, storage)
+		runtime.SetFinalizer(tape, func(t *storedTape) {
+			storage.Close()
+		})
+		return tape, writer
+	}
+}
+
+// CloseTape releases the resources used by a tape, such
+// as the file behind a tape from DiskTapeMaker.
+//
+// The tape should not be used after it is closed.
+// Tapes without any resources to release (e.g. from
+// lazyseq.ReferenceTape) are left alone.
+func CloseTape(t lazyseq.Tape) error {
+	if closer, ok := t.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// TapeSeqs reads the sequences from a tape into memory.
+//
+// The result is indexed by sequence and then by timestep.
+// The numSeqs argument is the number of sequences in the
+// tape (e.g. the number of episodes in a RolloutSet).
+func TapeSeqs(t lazyseq.Tape, numSeqs int) [][][]float64 {
+	c := t.Creator()
+	res := make([][][]float64, numSeqs)
+	for batch := range t.ReadTape(0, -1) {
+		if batch.NumPresent() == 0 {
+			continue
+		}
+		vals := c.Float64Slice(batch.Packed.Data())
+		size := len(vals) / batch.NumPresent()
+		for i, pres := range batch.Present {
+			if pres {
+				res[i] = append(res[i], vals[:size])
+				vals = vals[size:]
+			}
+		}
+	}
+	return res
+}
+
+// storedTape is a lazyseq.Tape which encodes its batches
+// and keeps them in a tapeStorage.
+type storedTape struct {
+	creator   anyvec.Creator
+	precision TapePrecision
+	compress  bool
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	storage tapeStorage
+	count   int
+	closed  bool
+	removed bool
+}
+
+func newStoredTape(c anyvec.Creator, p TapePrecision, compress bool,
+	storage tapeStorage) (*storedTape, chan<- *anyseq.Batch) {
+	t := &storedTape{
+		creator:   c,
+		precision: p,
+		compress:  compress,
+		storage:   storage,
+	}
+	t.cond = sync.NewCond(&t.lock)
+	writer := make(chan *anyseq.Batch, 1)
+	go func() {
+		for batch := range writer {
+			data := t.encode(batch)
+			t.lock.Lock()
+			if t.removed {
+				t.lock.Unlock()
+				continue
+			}
+			if err := t.storage.Append(data); err != nil {
+				t.lock.Unlock()
+				panic(essentials.AddCtx("write tape", err))
+			}
+			t.count++
+			t.cond.Broadcast()
+			t.lock.Unlock()
+		}
+		t.lock.Lock()
+		t.closed = true
+		t.cond.Broadcast()
+		t.lock.Unlock()
+	}()
+	return t, writer
+}
+
+// Creator returns the creator used to decode batches.
+func (t *storedTape) Creator() anyvec.Creator {
+	return t.creator
+}
+
+// ReadTape reads batches from the tape, waiting for them
+// to be written if necessary.
+func (t *storedTape) ReadTape(start, end int) <-chan *anyseq.Batch {
+	res := make(chan *anyseq.Batch, 1)
+	go func() {
+		defer close(res)
+		for i := start; end < 0 || i < end; i++ {
+			t.lock.Lock()
+			for i >= t.count && !t.closed {
+				t.cond.Wait()
+			}
+			if i >= t.count {
+				t.lock.Unlock()
+				return
+			}
+			if t.removed {
+				t.lock.Unlock()
+				panic("read tape: tape is closed")
+			}
+			data, err := t.storage.Get(i)
+			t.lock.Unlock()
+			if err != nil {
+				panic(essentials.AddCtx("read tape", err))
+			}
+			batch, err := t.decode(data)
+			if err != nil {
+				panic(essentials.AddCtx("read tape", err))
+			}
+			res <- batch
+		}
+	}()
+	return res
+}
+
+// Close releases the tape's storage.
+//
+// Batches written after the tape is closed are dropped,
+// and reading them causes a panic.
+func (t *storedTape) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.removed {
+		return nil
+	}
+	t.removed = true
+	runtime.SetFinalizer(t, nil)
+	if closer, ok := t.storage.(io.Closer); ok {
+		return closer.Close()
+	}
+	t.storage = nil
+	return nil
+}
+
+func (t *storedTape) encode(batch *anyseq.Batch) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(len(batch.Present)))
+	for _, p := range batch.Present {
+		if p {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	}
+	values := t.creator.Float64Slice(batch.Packed.Data())
+	binary.Write(&buf, binary.LittleEndian, uint32(len(values)))
+	switch t.precision {
+	case Float64Precision:
+		binary.Write(&buf, binary.LittleEndian, values)
+	case Float32Precision:
+		vals32 := make([]float32, len(values))
+		for i, x := range values {
+			vals32[i] = float32(x)
+		}
+		binary.Write(&buf, binary.LittleEndian, vals32)
+	case Float16Precision:
+		vals16 := make([]uint16, len(values))
+		for i, x := range values {
+			vals16[i] = float32ToFloat16(float32(x))
+		}
+		binary.Write(&buf, binary.LittleEndian, vals16)
+	default:
+		panic("unknown tape precision")
+	}
+
+	if !t.compress {
+		return buf.Bytes()
+	}
+	var compressed bytes.Buffer
+	w, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+	w.Write(buf.Bytes())
+	w.Close()
+	return compressed.Bytes()
+}
+
+func (t *storedTape) decode(data []byte) (batch *anyseq.Batch, err error) {
+	if t.compress {
+		data, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := bytes.NewReader(data)
+
+	var numSeqs uint32
+	if err := binary.Read(r, binary.LittleEndian, &numSeqs); err != nil {
+		return nil, err
+	}
+	presentData := make([]byte, numSeqs)
+	if _, err := io.ReadFull(r, presentData); err != nil {
+		return nil, err
+	}
+	present := make([]bool, numSeqs)
+	for i, p := range presentData {
+		present[i] = p != 0
+	}
+
+	var numValues uint32
+	if err := binary.Read(r, binary.LittleEndian, &numValues); err != nil {
+		return nil, err
+	}
+	values := make([]float64, numValues)
+	switch t.precision {
+	case Float64Precision:
+		err = binary.Read(r, binary.LittleEndian, values)
+	case Float32Precision:
+		vals32 := make([]float32, numValues)
+		err = binary.Read(r, binary.LittleEndian, vals32)
+		for i, x := range vals32 {
+			values[i] = float64(x)
+		}
+	case Float16Precision:
+		vals16 := make([]uint16, numValues)
+		err = binary.Read(r, binary.LittleEndian, vals16)
+		for i, x := range vals16 {
+			values[i] = float64(float16ToFloat32(x))
+		}
+	default:
+		return nil, errors.New("unknown tape precision")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &anyseq.Batch{
+		Packed:  t.creator.MakeVectorData(t.creator.MakeNumericList(values)),
+		Present: present,
+	}, nil
+}
+
+// tapeStorage stores encoded batches for a storedTape.
+type tapeStorage interface {
+	Append(data []byte) error
+	Get(idx int) ([]byte, error)
+}
+
+type memTapeStorage struct {
+	chunks [][]byte
+}
+
+func (m *memTapeStorage) Append(data []byte) error {
+	m.chunks = append(m.chunks, data)
+	return nil
+}
+
+func (m *memTapeStorage) Get(idx int) ([]byte, error) {
+	return m.chunks[idx], nil
+}
+
+type diskTapeStorage struct {
+	file    *os.File
+	offsets []int64
+	sizes   []int
+	size    int64
+}
+
+func (d *diskTapeStorage) Append(data []byte) error {
+	if _, err := d.file.WriteAt(data, d.size); err != nil {
+		return err
+	}
+	d.offsets = append(d.offsets, d.size)
+	d.sizes = append(d.sizes, len(data))
+	d.size += int64(len(data))
+	return nil
+}
+
+func (d *diskTapeStorage) Get(idx int) ([]byte, error) {
+	data := make([]byte, d.sizes[idx])
+	if _, err := d.file.ReadAt(data, d.offsets[idx]); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Close closes and deletes the file.
+func (d *diskTapeStorage) Close() error {
+	d.file.Close()
+	return os.Remove(d.file.Name())
+}
+
+// float32ToFloat16 converts a float32 to the bits of the
+// nearest IEEE half-precision float.
+func float32ToFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int((bits >> 23) & 0xff)
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	} else if e <= 0 {
+		// Subnormal (or zero) result.
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - e)
+		half := mant >> shift
+		rem := mant & ((1 << shift) - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	half := sign | uint16(e<<10) | uint16(mant>>13)
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		// A carry may overflow into the exponent, which is
+		// the correct behavior (possibly yielding infinity).
+		half++
+	}
+	return half
+}
+
+// float16ToFloat32 converts the bits of an IEEE
+// half-precision float to a float32.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	default:
+		return math.Float32frombits(sign | (exp+112)<<23 | mant<<13)
+	}
+}
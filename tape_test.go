@@ -0,0 +1,142 @@
+package anyrl
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec/anyvec64"
+	"github.com/unixpickle/lazyseq"
+)
+
+func TestFloat16(t *testing.T) {
+	exact := []float32{0, 1, -2, 0.5, 65504, -0.25, 1.0 / (1 << 24), 1.0 / (1 << 14)}
+	for _, x := range exact {
+		if actual := float16ToFloat32(float32ToFloat16(x)); actual != x {
+			t.Errorf("expected %v but got %v", x, actual)
+		}
+	}
+	inexact := map[float32]float32{
+		1.0001:      1,
+		3.14159:     3.140625,
+		1e6:         float32(math.Inf(1)),
+		-1e6:        float32(math.Inf(-1)),
+		1e-10:       0,
+		2049:        2048,
+		2051:        2052,
+		0.000012345: 0.000012338161,
+	}
+	for x, expected := range inexact {
+		actual := float16ToFloat32(float32ToFloat16(x))
+		if math.Abs(float64(actual-expected)) > 1e-10 {
+			t.Errorf("%v: expected %v but got %v", x, expected, actual)
+		}
+	}
+}
+
+func TestStoredTapes(t *testing.T) {
+	makers := map[string]TapeMaker{
+		"compressed":       CompressedTapeMaker(Float64Precision),
+		"compressed16":     CompressedTapeMaker(Float16Precision),
+		"disk":             DiskTapeMaker("", Float32Precision, false),
+		"diskCompressed":   DiskTapeMaker("", Float64Precision, true),
+		"diskCompressed16": DiskTapeMaker("", Float16Precision, true),
+	}
+	c := anyvec64.DefaultCreator{}
+	batches := []*anyseq.Batch{
+		{
+			Packed:  c.MakeVectorData([]float64{1, 0.5, -2, 0, 3, 4}),
+			Present: []bool{true, true, true},
+		},
+		{
+			Packed:  c.MakeVectorData([]float64{0.25, -1}),
+			Present: []bool{false, true, false},
+		},
+		{
+			Packed:  c.MakeVectorData([]float64{}),
+			Present: []bool{false, false, false},
+		},
+	}
+	for name, maker := range makers {
+		tape, writer := maker(c)
+		reader := tape.ReadTape(1, -1)
+		for _, batch := range batches {
+			writer <- batch
+		}
+		close(writer)
+		checkTapeBatches(t, name, batches[1:], reader)
+		checkTapeBatches(t, name, batches, tape.ReadTape(0, -1))
+		checkTapeBatches(t, name, batches[:2], tape.ReadTape(0, 2))
+	}
+}
+
+func TestDiskTapeClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "anyrl-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := anyvec64.DefaultCreator{}
+	tape, writer := DiskTapeMaker(dir, Float64Precision, false)(c)
+	batch := &anyseq.Batch{
+		Packed:  c.MakeVectorData([]float64{1, 2}),
+		Present: []bool{true, true},
+	}
+	writer <- batch
+	close(writer)
+	checkTapeBatches(t, "disk", []*anyseq.Batch{batch}, tape.ReadTape(0, -1))
+
+	if listing, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(listing) != 1 {
+		t.Fatalf("expected 1 file but got %d", len(listing))
+	}
+	if err := CloseTape(tape); err != nil {
+		t.Fatal(err)
+	}
+	if listing, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(listing) != 0 {
+		t.Errorf("expected no files but got %d", len(listing))
+	}
+	if err := CloseTape(tape); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+func checkTapeBatches(t *testing.T, name string, expected []*anyseq.Batch,
+	actual <-chan *anyseq.Batch) {
+	var idx int
+	for batch := range actual {
+		if idx >= len(expected) {
+			t.Errorf("%s: too many batches", name)
+			return
+		}
+		exp := expected[idx]
+		idx++
+		if len(batch.Present) != len(exp.Present) {
+			t.Errorf("%s: bad present list", name)
+			continue
+		}
+		for i, p := range batch.Present {
+			if p != exp.Present[i] {
+				t.Errorf("%s: bad present list", name)
+			}
+		}
+		if batch.Packed.Len() != exp.Packed.Len() {
+			t.Errorf("%s: bad vector length", name)
+			continue
+		}
+		if batch.Packed.Len() > 0 {
+			assertSimilar(t, batch.Packed, exp.Packed)
+		}
+	}
+	if idx != len(expected) {
+		t.Errorf("%s: expected %d batches but got %d", name, len(expected), idx)
+	}
+}
+
+var _ lazyseq.Tape = &storedTape{}
@@ -0,0 +1,291 @@
+package anyrl
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/unixpickle/anydiff/anyseq"
+	"github.com/unixpickle/anyvec"
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/lazyseq"
+)
+
+// A Trajectory is a recorded episode.
+type Trajectory struct {
+	// Observations contains the observation before every
+	// action, starting with the observation from Reset.
+	Observations [][]float64
+
+	// Actions contains the actions taken at every step.
+	Actions [][]float64
+
+	// Rewards contains the reward from every step.
+	Rewards []float64
+
+	// Done is true if the episode ended, as opposed to
+	// being cut off by the end of the recording.
+	Done bool
+
+	// Truncated is true if the episode was ended by a
+	// time limit.
+	Truncated bool
+
+	// FinalObs is the observation produced by the last
+	// step of the episode.
+	FinalObs []float64
+}
+
+// TrajectoryWriter encodes Trajectories to a stream.
+//
+// It is safe to use a TrajectoryWriter from multiple
+// Goroutines.
+type TrajectoryWriter struct {
+	lock sync.Mutex
+	enc  *gob.Encoder
+}
+
+// NewTrajectoryWriter creates a TrajectoryWriter which
+// writes to w.
+func NewTrajectoryWriter(w io.Writer) *TrajectoryWriter {
+	return &TrajectoryWriter{enc: gob.NewEncoder(w)}
+}
+
+// Write encodes a Trajectory.
+func (t *TrajectoryWriter) Write(traj *Trajectory) (err error) {
+	defer essentials.AddCtxTo("write trajectory", &err)
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.enc.Encode(traj)
+}
+
+// ReadTrajectories decodes all of the Trajectories from a
+// stream produced by a TrajectoryWriter.
+func ReadTrajectories(r io.Reader) (trajs []*Trajectory, err error) {
+	defer essentials.AddCtxTo("read trajectories", &err)
+	dec := gob.NewDecoder(r)
+	for {
+		var traj Trajectory
+		if err := dec.Decode(&traj); err == io.EOF {
+			return trajs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		trajs = append(trajs, &traj)
+	}
+}
+
+// TrajectoryRolloutSet converts Trajectories into a
+// RolloutSet with one episode per Trajectory.
+//
+// The resulting RolloutSet has no AgentOuts.
+func TrajectoryRolloutSet(c anyvec.Creator, trajs []*Trajectory) *RolloutSet {
+	inputs := make([][][]float64, len(trajs))
+	actions := make([][][]float64, len(trajs))
+	res := &RolloutSet{Rewards: make(Rewards, len(trajs))}
+	for i, traj := range trajs {
+		inputs[i] = traj.Observations[:len(traj.Actions)]
+		actions[i] = traj.Actions
+		res.Rewards[i] = traj.Rewards
+		if traj.Truncated {
+			if res.Truncated == nil {
+				res.Truncated = make([]bool, len(trajs))
+				res.FinalObs = make([][]float64, len(trajs))
+			}
+			res.Truncated[i] = true
+			res.FinalObs[i] = traj.FinalObs
+		}
+	}
+	res.Inputs = vectorSeqTape(c, inputs)
+	res.Actions = vectorSeqTape(c, actions)
+	return res
+}
+
+// RecorderEnv wraps an Env and records its episodes.
+//
+// Every episode is written to Writer once it is done.
+// Unfinished episodes are never written.
+type RecorderEnv struct {
+	Env
+	Writer *TrajectoryWriter
+
+	traj *Trajectory
+}
+
+// Reset resets the environment.
+func (r *RecorderEnv) Reset() ([]float64, error) {
+	return r.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (r *RecorderEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	obs, err := EnvResetCtx(ctx, r.Env)
+	if err != nil {
+		return nil, err
+	}
+	r.traj = &Trajectory{Observations: [][]float64{copyVec(obs)}}
+	return obs, nil
+}
+
+// Step takes a step in the environment.
+func (r *RecorderEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return r.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+//
+// If the step fails, the unfinished episode is dropped.
+func (r *RecorderEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	if r.traj == nil {
+		return nil, 0, false, errors.New("step: environment must be reset")
+	}
+	obs, rew, done, err := EnvStepCtx(ctx, r.Env, action)
+	if err != nil {
+		r.traj = nil
+		return nil, 0, false, err
+	}
+	r.traj.Actions = append(r.traj.Actions, copyVec(action))
+	r.traj.Rewards = append(r.traj.Rewards, rew)
+	if !done {
+		r.traj.Observations = append(r.traj.Observations, copyVec(obs))
+		return obs, rew, done, nil
+	}
+	r.traj.Done = true
+	r.traj.Truncated = EnvTruncated(r.Env)
+	r.traj.FinalObs = copyVec(obs)
+	traj := r.traj
+	r.traj = nil
+	if err := r.Writer.Write(traj); err != nil {
+		return nil, 0, false, err
+	}
+	return obs, rew, done, nil
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (r *RecorderEnv) Truncated() bool {
+	return EnvTruncated(r.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (r *RecorderEnv) StepInfo() interface{} {
+	return EnvStepInfo(r.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (r *RecorderEnv) Seed(seed int64) {
+	SeedEnv(r.Env, seed)
+}
+
+// ReplayEnv is an Env which replays recorded
+// Trajectories.
+//
+// Each episode replays the next Trajectory, cycling back
+// to the first one after the last.
+// Every action must match the recorded action, making
+// ReplayEnv useful for checking that an agent behaves
+// deterministically.
+type ReplayEnv struct {
+	Trajectories []*Trajectory
+
+	// Epsilon is the maximum absolute difference between
+	// an action component and its recorded value.
+	Epsilon float64
+
+	trajIdx  int
+	timestep int
+	traj     *Trajectory
+}
+
+// Reset starts replaying the next Trajectory.
+func (r *ReplayEnv) Reset() ([]float64, error) {
+	if len(r.Trajectories) == 0 {
+		return nil, errors.New("reset: no trajectories to replay")
+	}
+	r.traj = r.Trajectories[r.trajIdx]
+	r.trajIdx = (r.trajIdx + 1) % len(r.Trajectories)
+	r.timestep = 0
+	return copyVec(r.traj.Observations[0]), nil
+}
+
+// Step replays the next timestep.
+//
+// It fails if the action does not match the recorded
+// action.
+func (r *ReplayEnv) Step(action []float64) (obs []float64, rew float64,
+	done bool, err error) {
+	if r.traj == nil || r.timestep >= len(r.traj.Actions) {
+		return nil, 0, false, errors.New("step: no recorded timestep")
+	}
+	expected := r.traj.Actions[r.timestep]
+	if len(action) != len(expected) {
+		return nil, 0, false, fmt.Errorf("step %d: expected action of length %d "+
+			"but got %d", r.timestep, len(expected), len(action))
+	}
+	for i, x := range action {
+		if math.Abs(x-expected[i]) > r.Epsilon {
+			return nil, 0, false, fmt.Errorf("step %d: action does not match recording",
+				r.timestep)
+		}
+	}
+	rew = r.traj.Rewards[r.timestep]
+	r.timestep++
+	if r.timestep == len(r.traj.Actions) {
+		if !r.traj.Done {
+			return nil, 0, false, errors.New("step: recording ended mid-episode")
+		}
+		return copyVec(r.traj.FinalObs), rew, true, nil
+	}
+	return copyVec(r.traj.Observations[r.timestep]), rew, false, nil
+}
+
+// Truncated returns whether the current Trajectory was
+// recorded as truncated.
+func (r *ReplayEnv) Truncated() bool {
+	return r.traj != nil && r.timestep == len(r.traj.Actions) && r.traj.Truncated
+}
+
+// vectorSeqTape creates a tape from a batch of vector
+// sequences.
+func vectorSeqTape(c anyvec.Creator, seqs [][][]float64) lazyseq.Tape {
+	res, writer := lazyseq.ReferenceTape(c)
+	writeVectorSeqs(c, writer, seqs)
+	close(writer)
+	return res
+}
+
+// writeVectorSeqs writes a batch of vector sequences to
+// a tape's writer.
+func writeVectorSeqs(c anyvec.Creator, writer chan<- *anyseq.Batch,
+	seqs [][][]float64) {
+	var t int
+	for {
+		present := make([]bool, len(seqs))
+		var packed []float64
+		for seqIdx, seq := range seqs {
+			if t < len(seq) {
+				present[seqIdx] = true
+				packed = append(packed, seq[t]...)
+			}
+		}
+		if len(packed) == 0 {
+			break
+		}
+		writer <- &anyseq.Batch{
+			Packed:  c.MakeVectorData(c.MakeNumericList(packed)),
+			Present: present,
+		}
+		t++
+	}
+}
+
+func copyVec(vec []float64) []float64 {
+	return append([]float64{}, vec...)
+}
@@ -0,0 +1,76 @@
+package anyrl
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/anyvec/anyvec64"
+)
+
+func TestTrajectoryRecordReplay(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewTrajectoryWriter(&buf)
+	for _, epLen := range []int{3, 1} {
+		env := &RecorderEnv{
+			Env:    &rnnTestEnv{RewardScale: 1, EpLen: epLen, Observation: []float64{1, 2}},
+			Writer: writer,
+		}
+		if _, err := env.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < epLen; i++ {
+			if _, _, _, err := env.Step([]float64{0, float64(i % 2)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	trajs, err := ReadTrajectories(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trajs) != 2 {
+		t.Fatalf("expected 2 trajectories but got %d", len(trajs))
+	}
+	if expected := []float64{0, 1, 0}; !reflect.DeepEqual(trajs[0].Rewards, expected) {
+		t.Errorf("expected rewards %v but got %v", expected, trajs[0].Rewards)
+	}
+	if expected := []float64{3, 6}; !reflect.DeepEqual(trajs[0].FinalObs, expected) {
+		t.Errorf("expected final obs %v but got %v", expected, trajs[0].FinalObs)
+	}
+
+	replay := &ReplayEnv{Trajectories: trajs}
+	for i, traj := range trajs {
+		obs, err := replay.Reset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(obs, traj.Observations[0]) {
+			t.Errorf("trajectory %d: unexpected initial observation %v", i, obs)
+		}
+		for j, action := range traj.Actions {
+			_, rew, done, err := replay.Step(action)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rew != traj.Rewards[j] || done != (j == len(traj.Actions)-1) {
+				t.Errorf("trajectory %d step %d: unexpected reward %f or done %v",
+					i, j, rew, done)
+			}
+		}
+	}
+	replay.Reset()
+	if _, _, _, err := replay.Step([]float64{1, 0}); err == nil {
+		t.Error("expected error for mismatched action")
+	}
+
+	rollouts := TrajectoryRolloutSet(anyvec64.DefaultCreator{}, trajs)
+	if rollouts.NumSteps() != 4 {
+		t.Errorf("expected 4 steps but got %d", rollouts.NumSteps())
+	}
+	batch := <-rollouts.Inputs.ReadTape(1, 2)
+	if expected := []bool{true, false}; !reflect.DeepEqual(batch.Present, expected) {
+		t.Errorf("expected present %v but got %v", expected, batch.Present)
+	}
+}
@@ -1,7 +1,9 @@
 package anyrl
 
 import (
+	"context"
 	"errors"
+	"math/rand"
 )
 
 // MetaEnv is a meta-learning environment in which
@@ -34,12 +36,20 @@ type MetaEnv struct {
 	RewardAll bool
 
 	runsRemaining int
+	truncated     bool
 }
 
 // Reset resets the environment.
-func (m *MetaEnv) Reset() (obs []float64, err error) {
+func (m *MetaEnv) Reset() ([]float64, error) {
+	return m.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (m *MetaEnv) ResetCtx(ctx context.Context) (obs []float64, err error) {
 	m.runsRemaining = m.NumRuns
-	obs, err = m.Env.Reset()
+	m.truncated = false
+	obs, err = EnvResetCtx(ctx, m.Env)
 	if err != nil {
 		return
 	}
@@ -49,13 +59,19 @@ func (m *MetaEnv) Reset() (obs []float64, err error) {
 }
 
 // Step takes a step in the environment.
-func (m *MetaEnv) Step(act []float64) (obs []float64, rew float64,
-	done bool, err error) {
+func (m *MetaEnv) Step(act []float64) ([]float64, float64, bool, error) {
+	return m.StepCtx(context.Background(), act)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (m *MetaEnv) StepCtx(ctx context.Context, act []float64) (obs []float64,
+	rew float64, done bool, err error) {
 	if m.runsRemaining <= 0 {
 		err = errors.New("step: done sub-episodes in meta-environment")
 		return
 	}
-	obs, rew, done, err = m.Env.Step(act)
+	obs, rew, done, err = EnvStepCtx(ctx, m.Env, act)
 	if err != nil {
 		return
 	}
@@ -64,8 +80,9 @@ func (m *MetaEnv) Step(act []float64) (obs []float64, rew float64,
 		rewDoneVec[1] = 1
 		m.runsRemaining--
 		done = m.runsRemaining == 0
+		m.truncated = done && EnvTruncated(m.Env)
 		if !done {
-			obs, err = m.Env.Reset()
+			obs, err = EnvResetCtx(ctx, m.Env)
 			if err != nil {
 				return
 			}
@@ -78,27 +95,290 @@ func (m *MetaEnv) Step(act []float64) (obs []float64, rew float64,
 	return
 }
 
+// Seed seeds the wrapped Env if it is a Seeder.
+func (m *MetaEnv) Seed(seed int64) {
+	SeedEnv(m.Env, seed)
+}
+
+// Truncated returns true if the last sub-episode of the
+// meta-episode was truncated.
+func (m *MetaEnv) Truncated() bool {
+	return m.truncated
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (m *MetaEnv) StepInfo() interface{} {
+	return EnvStepInfo(m.Env)
+}
+
 // MaxStepsEnv wraps an Env and ends episodes early if
 // they run longer than MaxSteps timesteps.
+//
+// Episodes which are ended early are reported as
+// truncated via the Truncater interface.
 type MaxStepsEnv struct {
 	Env
 	MaxSteps int
 
-	steps int
+	steps     int
+	truncated bool
 }
 
 // Reset resets the environment.
 func (m *MaxStepsEnv) Reset() ([]float64, error) {
+	return m.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (m *MaxStepsEnv) ResetCtx(ctx context.Context) ([]float64, error) {
 	m.steps = 0
-	return m.Env.Reset()
+	m.truncated = false
+	return EnvResetCtx(ctx, m.Env)
 }
 
 // Step takes a step in the environment.
 func (m *MaxStepsEnv) Step(action []float64) ([]float64, float64, bool, error) {
-	obs, rew, done, err := m.Env.Step(action)
+	return m.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (m *MaxStepsEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	obs, rew, done, err := EnvStepCtx(ctx, m.Env, action)
 	m.steps++
-	if m.steps == m.MaxSteps {
+	if done {
+		m.truncated = EnvTruncated(m.Env)
+	} else if m.steps == m.MaxSteps {
 		done = true
+		m.truncated = true
 	}
 	return obs, rew, done, err
 }
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (m *MaxStepsEnv) Seed(seed int64) {
+	SeedEnv(m.Env, seed)
+}
+
+// Truncated returns true if the last episode was ended
+// early, either by MaxSteps or by the wrapped Env.
+func (m *MaxStepsEnv) Truncated() bool {
+	return m.truncated
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (m *MaxStepsEnv) StepInfo() interface{} {
+	return EnvStepInfo(m.Env)
+}
+
+// FrameStackEnv wraps an Env and concatenates the last
+// NumFrames observations to produce each observation.
+//
+// The oldest observation comes first.
+// At the start of an episode, the initial observation is
+// repeated to fill the stack.
+type FrameStackEnv struct {
+	Env
+
+	// NumFrames is the number of stacked frames.
+	// It must be at least 1.
+	NumFrames int
+
+	frames [][]float64
+}
+
+// Reset resets the environment.
+func (f *FrameStackEnv) Reset() ([]float64, error) {
+	return f.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (f *FrameStackEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	if f.NumFrames < 1 {
+		return nil, errors.New("NumFrames must be at least 1")
+	}
+	obs, err := EnvResetCtx(ctx, f.Env)
+	if err != nil {
+		return nil, err
+	}
+	f.frames = make([][]float64, f.NumFrames)
+	for i := range f.frames {
+		f.frames[i] = obs
+	}
+	return f.joinedFrames(), nil
+}
+
+// Step takes a step in the environment.
+func (f *FrameStackEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return f.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (f *FrameStackEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	obs, rew, done, err := EnvStepCtx(ctx, f.Env, action)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	f.frames = append(f.frames[1:], obs)
+	return f.joinedFrames(), rew, done, nil
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (f *FrameStackEnv) Truncated() bool {
+	return EnvTruncated(f.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (f *FrameStackEnv) StepInfo() interface{} {
+	return EnvStepInfo(f.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (f *FrameStackEnv) Seed(seed int64) {
+	SeedEnv(f.Env, seed)
+}
+
+func (f *FrameStackEnv) joinedFrames() []float64 {
+	var res []float64
+	for _, frame := range f.frames {
+		res = append(res, frame...)
+	}
+	return res
+}
+
+// ActionRepeatEnv wraps an Env and repeats every action
+// NumRepeats times, summing the rewards.
+//
+// The repetition stops early if the episode ends.
+type ActionRepeatEnv struct {
+	Env
+
+	// NumRepeats is the number of times to repeat each
+	// action.
+	// It must be at least 1.
+	NumRepeats int
+}
+
+// Step takes NumRepeats steps in the environment.
+func (a *ActionRepeatEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return a.StepCtx(context.Background(), action)
+}
+
+// ResetCtx resets the environment, passing the context to
+// the wrapped Env.
+func (a *ActionRepeatEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	return EnvResetCtx(ctx, a.Env)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (a *ActionRepeatEnv) StepCtx(ctx context.Context, action []float64) (obs []float64,
+	rew float64, done bool, err error) {
+	if a.NumRepeats < 1 {
+		return nil, 0, false, errors.New("NumRepeats must be at least 1")
+	}
+	for i := 0; i < a.NumRepeats; i++ {
+		var stepRew float64
+		obs, stepRew, done, err = EnvStepCtx(ctx, a.Env, action)
+		if err != nil {
+			return
+		}
+		rew += stepRew
+		if done {
+			break
+		}
+	}
+	return
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (a *ActionRepeatEnv) Truncated() bool {
+	return EnvTruncated(a.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (a *ActionRepeatEnv) StepInfo() interface{} {
+	return EnvStepInfo(a.Env)
+}
+
+// Seed seeds the wrapped Env if it is a Seeder.
+func (a *ActionRepeatEnv) Seed(seed int64) {
+	SeedEnv(a.Env, seed)
+}
+
+// StickyActionsEnv wraps an Env and, with probability
+// StickProb, ignores the requested action and repeats the
+// previous action instead.
+//
+// The first action of every episode is never ignored.
+type StickyActionsEnv struct {
+	Env
+	StickProb float64
+
+	// Rand is used to decide when to repeat actions.
+	// If nil, the global math/rand generator is used.
+	Rand *rand.Rand
+
+	lastAction []float64
+}
+
+// Reset resets the environment.
+func (s *StickyActionsEnv) Reset() ([]float64, error) {
+	return s.ResetCtx(context.Background())
+}
+
+// ResetCtx is like Reset, but it passes the context to
+// the wrapped Env.
+func (s *StickyActionsEnv) ResetCtx(ctx context.Context) ([]float64, error) {
+	s.lastAction = nil
+	return EnvResetCtx(ctx, s.Env)
+}
+
+// Step takes a step in the environment.
+func (s *StickyActionsEnv) Step(action []float64) ([]float64, float64, bool, error) {
+	return s.StepCtx(context.Background(), action)
+}
+
+// StepCtx is like Step, but it passes the context to the
+// wrapped Env.
+func (s *StickyActionsEnv) StepCtx(ctx context.Context, action []float64) ([]float64,
+	float64, bool, error) {
+	if s.lastAction != nil && s.sample() < s.StickProb {
+		action = s.lastAction
+	} else {
+		s.lastAction = append([]float64{}, action...)
+	}
+	return EnvStepCtx(ctx, s.Env, action)
+}
+
+// Truncated returns whether the wrapped Env truncated the
+// last episode.
+func (s *StickyActionsEnv) Truncated() bool {
+	return EnvTruncated(s.Env)
+}
+
+// StepInfo returns the step info from the wrapped Env.
+func (s *StickyActionsEnv) StepInfo() interface{} {
+	return EnvStepInfo(s.Env)
+}
+
+// Seed sets Rand using the seed, and seeds the wrapped
+// Env with a seed derived from Rand.
+func (s *StickyActionsEnv) Seed(seed int64) {
+	s.Rand = rand.New(rand.NewSource(seed))
+	SeedEnv(s.Env, s.Rand.Int63())
+}
+
+func (s *StickyActionsEnv) sample() float64 {
+	if s.Rand == nil {
+		return rand.Float64()
+	}
+	return s.Rand.Float64()
+}
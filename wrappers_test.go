@@ -0,0 +1,106 @@
+package anyrl
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFrameStackEnv(t *testing.T) {
+	env := &FrameStackEnv{
+		Env:       &rnnTestEnv{EpLen: 5, Observation: []float64{1, 2}},
+		NumFrames: 3,
+	}
+	obs, err := env.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []float64{1, 2, 1, 2, 1, 2}; !reflect.DeepEqual(obs, expected) {
+		t.Errorf("expected %v but got %v", expected, obs)
+	}
+	for i := 0; i < 2; i++ {
+		obs, _, _, err = env.Step([]float64{1})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := []float64{1, 2, 1, 2, 2, 4}; !reflect.DeepEqual(obs, expected) {
+		t.Errorf("expected %v but got %v", expected, obs)
+	}
+}
+
+func TestFrameStackEnvNoFrames(t *testing.T) {
+	env := &FrameStackEnv{Env: &rnnTestEnv{EpLen: 5, Observation: []float64{1}}}
+	if _, err := env.Reset(); err == nil {
+		t.Error("expected error for zero NumFrames")
+	}
+}
+
+func TestActionRepeatEnv(t *testing.T) {
+	env := &ActionRepeatEnv{
+		Env:        &rnnTestEnv{RewardScale: 1, EpLen: 5, Observation: []float64{1}},
+		NumRepeats: 3,
+	}
+	if _, err := env.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	var rewards []float64
+	var obs []float64
+	for {
+		var rew float64
+		var done bool
+		var err error
+		obs, rew, done, err = env.Step([]float64{0, 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rewards = append(rewards, rew)
+		if done {
+			break
+		}
+	}
+	if expected := []float64{3, 2}; !reflect.DeepEqual(rewards, expected) {
+		t.Errorf("expected rewards %v but got %v", expected, rewards)
+	}
+	if expected := []float64{5}; !reflect.DeepEqual(obs, expected) {
+		t.Errorf("expected final observation %v but got %v", expected, obs)
+	}
+}
+
+func TestStickyActionsEnv(t *testing.T) {
+	runRewards := func(seed int64) []float64 {
+		env := &StickyActionsEnv{
+			Env:       &rnnTestEnv{RewardScale: 1, EpLen: 100, Observation: []float64{1}},
+			StickProb: 0.5,
+			Rand:      rand.New(rand.NewSource(seed)),
+		}
+		if _, err := env.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		var rewards []float64
+		for i := 0; i < 100; i++ {
+			action := []float64{0, 0}
+			action[i%2] = 1
+			_, rew, _, err := env.Step(action)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rewards = append(rewards, rew)
+		}
+		return rewards
+	}
+
+	rewards := runRewards(1337)
+	if !reflect.DeepEqual(rewards, runRewards(1337)) {
+		t.Error("results are not reproducible")
+	}
+	var numSticky int
+	for i, rew := range rewards {
+		if rew != float64(i%2) {
+			numSticky++
+		}
+	}
+	if numSticky < 10 || numSticky > 60 {
+		t.Errorf("unexpected number of sticky actions: %d", numSticky)
+	}
+}